@@ -1,5 +1,10 @@
 module gitlab.com/rbrt-weiler/sophos-sg-smtp-logparser
 
-go 1.24
+go 1.24.0
 
-require github.com/spf13/pflag v1.0.6
+require (
+	github.com/spf13/pflag v1.0.6
+	golang.org/x/net v0.47.0
+)
+
+require golang.org/x/text v0.31.0 // indirect
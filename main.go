@@ -2,18 +2,20 @@ package main
 
 import (
 	"embed"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"os"
 	"path"
-	"regexp"
 	"runtime"
-	"sort"
+	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
 	pflag "github.com/spf13/pflag"
+	"gitlab.com/rbrt-weiler/sophos-sg-smtp-logparser/pkg/sglog"
 )
 
 /*
@@ -43,6 +45,14 @@ const (
 	errGzipWrite  int = 21 // Gzip stream could not be written to
 	errGzipFlush  int = 22 // Gzip stream could not be synced
 	errGzipClose  int = 23 // Gzip stream could not be closed
+	errRender     int = 30 // Output could not be rendered
+	errFileOpen   int = 40 // Logfile could not be opened
+	errFileRead   int = 41 // Logfile could not be read completely
+	errGzipRead   int = 42 // Gzip'ed logfile is corrupt
+	errSkipRatio  int = 43 // Too many log lines could not be parsed
+	errDataDecode int = 44 // Data file could not be decoded
+	errNoMails    int = 45 // No mail could be parsed in strict mode
+	errSend       int = 50 // Output could not be sent
 )
 
 /*
@@ -59,10 +69,57 @@ const (
 type appConfig struct {
 	SpareThreads   int
 	SliceSize      int
+	Command        string
 	LogFiles       stringArray
+	DataFiles      stringArray
 	InternalHosts  stringArray
+	SpecialUsers   stringArray
+	Normalize      bool
+	LowerDomains   bool
+	LowerLocal     bool
+	StripSubaddr   bool
+	UnwrapAddrs    bool
 	NoCSVHeader    bool
+	CSVFields      string
+	SortBy         string
 	JSONOutput     bool
+	HTMLOutput     bool
+	ReportTitle    string
+	OutputFormat   string
+	ElasticIndex   string
+	ElasticURL     string
+	TimeZone       string
+	Location       *time.Location
+	SyslogAddress  string
+	GraphNodes     string
+	GraphWeight    string
+	TopLimit       int
+	TopRankBy      string
+	TopType        string
+	DiffThreshold  float64
+	Threads        bool
+	ResponseTimes  bool
+	HoursReport    bool
+	SizeReport     bool
+	LargeMail      string
+	LargeMailSize  int64
+	WorkHours      string
+	Weekend        string
+	HolidayFiles   stringArray
+	BulkFilter     string
+	BulkMinRcpts   int
+	BaselineFiles  stringArray
+	AnomalyBucket  string
+	AnomalyScore   float64
+	Pseudonymize   bool
+	PseudoKeyFile  string
+	PseudoDomains  bool
+	SubjectMode    string
+	RejectsFile    string
+	Strict         bool
+	MaxSkipRatio   float64
+	TemplateFile   string
+	TemplateScope  string
 	OutfileName    string
 	CompressOutput bool
 	CreateTestdata bool
@@ -80,25 +137,12 @@ type appConfig struct {
 */
 
 var (
-	config appConfig  // Holds config as defined by CLI arguments.
-	lb     lineBuffer // Stores log lines that should be parsed
-	mb     mailBuffer // Temporary storage for parsed mails
-	mails  mailData   // Data structure for storing parsed results.
+	config appConfig // Holds config as defined by CLI arguments.
 
-	stdOut = log.New(os.Stdout, "", log.LstdFlags) // Shortcut for CLI output.
 	stdErr = log.New(os.Stderr, "", log.LstdFlags) // Shortcut for CLI output.
-
-	// Regular expressions used for parsing a single log line.
-	reDateTime   = regexp.MustCompile(`^(.+?)-(.+?)\s`)
-	reValidEmail = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
-	reFrom       = regexp.MustCompile(`\sfrom="(.*?)"\s?`)
-	reTo         = regexp.MustCompile(`\sto="(.*?)"\s?`)
-	reSubject    = regexp.MustCompile(`\ssubject="(.*?)"\s?`)
-	reSize       = regexp.MustCompile(`\ssize="(.+?)"\s?`)
-	reQueueID    = regexp.MustCompile(`\squeueid="(.+?)"\s?`)
 )
 
-//go:embed embedded-testdata.txt
+//go:embed embedded-testdata.txt embedded-report.html
 var embedFS embed.FS
 
 /*
@@ -116,8 +160,51 @@ func parseCLIOptions() {
 	pflag.IntVar(&config.SpareThreads, "sparethreads", 2, "Threads to keep free for other programs")
 	pflag.IntVar(&config.SliceSize, "slicesize", 100, "Size of internal parsing slices")
 	pflag.VarP(&config.InternalHosts, "internalhost", "i", "Host part to be considered as internal")
+	pflag.Var(&config.SpecialUsers, "special-address", "Local part of addresses to be considered as special (e.g. postmaster)")
+	pflag.BoolVar(&config.Normalize, "normalize", false, "Lower case addresses, strip subaddress tags and unwrap rewritten addresses")
+	pflag.BoolVar(&config.LowerDomains, "lowercase-domains", false, "Convert domains to lower case")
+	pflag.BoolVar(&config.LowerLocal, "lowercase-localparts", false, "Convert local parts of addresses to lower case")
+	pflag.BoolVar(&config.StripSubaddr, "strip-subaddress", false, "Remove subaddress tags like +tag from local parts")
+	pflag.BoolVar(&config.UnwrapAddrs, "unwrap-addresses", false, "Restore original addresses rewritten by SRS or BATV")
 	pflag.BoolVar(&config.NoCSVHeader, "no-csv-header", false, "Omit CSV header line")
-	pflag.BoolVarP(&config.JSONOutput, "json", "J", false, "Output in JSON format")
+	pflag.StringVar(&config.CSVFields, "fields", "", "Comma-separated list of partner fields to write as CSV columns, in this order")
+	pflag.StringVar(&config.SortBy, "sort", "partner", "Order of partners in CSV output: partner, count or bytes")
+	pflag.BoolVarP(&config.JSONOutput, "json", "J", false, "Output in JSON format (same as --format=json)")
+	pflag.BoolVar(&config.HTMLOutput, "html", false, "Output as HTML report (same as --format=html)")
+	pflag.StringVar(&config.ReportTitle, "report-title", "Mail traffic report", "Title of the HTML report")
+	pflag.StringVar(&config.OutputFormat, "format", "csv", "Output format: csv, json, table, html, dot, gexf, graphml, elastic, cef or leef")
+	pflag.StringVar(&config.ElasticIndex, "elastic-index", "mail-%Y.%m.%d", "Index name pattern for elastic output; %Y, %m, %d and %H are taken from the mail date")
+	pflag.StringVar(&config.TimeZone, "timezone", "Local", "Time zone of the logfiles for timestamps in elastic output, e.g. Europe/Berlin")
+	pflag.StringVar(&config.SyslogAddress, "syslog", "", "Send cef or leef output to the syslog collector at this address, e.g. udp://siem.example.com:514")
+	pflag.StringVar(&config.ElasticURL, "elastic-url", "", "Send elastic output to the Elasticsearch or OpenSearch server at this URL instead of printing it")
+	pflag.StringVar(&config.GraphNodes, "graph-nodes", "address", "Nodes in graph output: address or domain")
+	pflag.StringVar(&config.GraphWeight, "graph-weight", "count", "Weight edges in graph output by mail count or size: count or size")
+	pflag.IntVar(&config.TopLimit, "top", 0, "Create a report of the N most active senders, recipients, partners and domains")
+	pflag.StringVar(&config.TopRankBy, "top-by", "count", "Rank top report by mail count or size: count or size")
+	pflag.StringVar(&config.TopType, "top-type", "", "Only consider mails of this type for top report (e.g. i2e)")
+	pflag.Float64Var(&config.DiffThreshold, "diff-threshold", 0.5, "Report partners whose mail count or size changed by more than this ratio (with diff)")
+	pflag.BoolVar(&config.Threads, "threads", false, "Create a report of the conversations between partners, grouped by subject")
+	pflag.BoolVar(&config.ResponseTimes, "response-times", false, "Create a report of the response times between two-way partners")
+	pflag.BoolVar(&config.SizeReport, "sizes", false, "Create a report of the size distribution per partner and overall")
+	pflag.StringVar(&config.LargeMail, "large-mail", "", "Create a report of all mails of at least this size (e.g. 10M)")
+	pflag.BoolVar(&config.HoursReport, "hours", false, "Create a report of mails sent in and outside of working hours per sender and partner")
+	pflag.StringVar(&config.WorkHours, "work-hours", "08:00-18:00", "Working hours on working days")
+	pflag.StringVar(&config.Weekend, "weekend", "sat,sun", "Comma-separated list of days without working hours")
+	pflag.Var(&config.HolidayFiles, "holidays", "File containing holidays as ISO dates, one per line (can be repeated)")
+	pflag.StringVar(&config.BulkFilter, "bulk", "include", "Handling of mails from bulk and automated senders: include, exclude or only")
+	pflag.IntVar(&config.BulkMinRcpts, "bulk-recipients", 20, "Minimum number of recipients for classifying a sender as bulk by recipients or subjects")
+	pflag.Var(&config.BaselineFiles, "baseline", "JSON output of a previous run to detect anomalies against (can be repeated)")
+	pflag.StringVar(&config.AnomalyBucket, "anomaly-bucket", "day", "Time bucket for anomaly detection: day or hour (with --baseline)")
+	pflag.Float64Var(&config.AnomalyScore, "anomaly-score", 3, "Minimum score of reported anomalies (with --baseline)")
+	pflag.BoolVar(&config.Pseudonymize, "pseudonymize", false, "Replace addresses with keyed pseudonyms")
+	pflag.StringVar(&config.PseudoKeyFile, "pseudonymize-key-file", "", "File containing the key for --pseudonymize (default $"+pseudonymKeyEnv+")")
+	pflag.BoolVar(&config.PseudoDomains, "pseudonymize-domains", false, "Also replace domains with pseudonyms (with --pseudonymize)")
+	pflag.StringVar(&config.SubjectMode, "subjects", "keep", "Handling of subjects: keep, redact or hash")
+	pflag.StringVar(&config.RejectsFile, "rejects-file", "", "File to write log lines that could not be parsed to")
+	pflag.BoolVar(&config.Strict, "strict", false, "Abort without output on unreadable logfiles or skipped lines")
+	pflag.Float64Var(&config.MaxSkipRatio, "max-skip-ratio", -1, "Fail if the ratio of skipped to relevant lines exceeds this value (0 to 1, default 0 with --strict)")
+	pflag.StringVar(&config.TemplateFile, "template", "", "Go text/template file to render the output with instead of --format")
+	pflag.StringVar(&config.TemplateScope, "template-scope", "data", "Execute template once for all data, once per partner or once per mail: data, partner or mail")
 	pflag.StringVarP(&config.OutfileName, "outfile", "o", "", "File to write data to instead of stdout")
 	pflag.BoolVarP(&config.CompressOutput, "compress-outfile", "Z", false, "Compress output (with -o)")
 	pflag.BoolVar(&config.CreateTestdata, "create-testdata", false, "Create test data")
@@ -128,17 +215,56 @@ func parseCLIOptions() {
 		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "This tool parses a number of Sophos SG SMTP logfiles (uncompressed and\n")
 		fmt.Fprintf(os.Stderr, "gzip'ed) and provides an overview of the e-mails sent and received. It\n")
-		fmt.Fprintf(os.Stderr, "supports two output formats:\n")
+		fmt.Fprintf(os.Stderr, "supports the following output formats:\n")
 		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "- CSV (the default) provides a CSV-styled list of communication partners\n")
 		fmt.Fprintf(os.Stderr, "  and their associated mail volume (count and bytes). It is intended to\n")
 		fmt.Fprintf(os.Stderr, "  give administrators a quick overview of the mail traffic.\n")
 		fmt.Fprintf(os.Stderr, "- JSON provides a very detailed representation of the e-mails sent between\n")
 		fmt.Fprintf(os.Stderr, "  communication partners. It is intended to be used by another program.\n")
+		fmt.Fprintf(os.Stderr, "- Table provides a summary of the mail traffic, the busiest partners and\n")
+		fmt.Fprintf(os.Stderr, "  hours. It is intended to be read by administrators on the terminal.\n")
+		fmt.Fprintf(os.Stderr, "- HTML provides a self-contained report with sortable partner tables, a\n")
+		fmt.Fprintf(os.Stderr, "  timeline and all mails per partner. It is intended to be handed out.\n")
+		fmt.Fprintf(os.Stderr, "- DOT, GEXF and GraphML provide a directed graph of the communication\n")
+		fmt.Fprintf(os.Stderr, "  partners. They are intended to be visualized with Graphviz or Gephi.\n")
+		fmt.Fprintf(os.Stderr, "- Elastic provides every mail as Elasticsearch bulk request. It is intended\n")
+		fmt.Fprintf(os.Stderr, "  to be imported into Elasticsearch or OpenSearch.\n")
+		fmt.Fprintf(os.Stderr, "- CEF and LEEF provide every mail as security event. They are intended to\n")
+		fmt.Fprintf(os.Stderr, "  be correlated with other events in ArcSight, QRadar or another SIEM.\n")
+		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "With --top, a ranking of the most active senders, recipients, partners and\n")
+		fmt.Fprintf(os.Stderr, "domains is created instead, using the same output formats.\n")
+		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "With --threads, the mails of every partner are grouped into conversations\n")
+		fmt.Fprintf(os.Stderr, "by their subject instead, along with the time it took to respond.\n")
+		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "With --response-times, median and percentiles of the time partners take to\n")
+		fmt.Fprintf(os.Stderr, "respond to each other are reported per partner and internal domain.\n")
+		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "With --hours, mails and bytes sent in and outside of working hours are\n")
+		fmt.Fprintf(os.Stderr, "reported per sender and partner.\n")
+		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "With --sizes, the distribution of mail sizes is reported per partner and\n")
+		fmt.Fprintf(os.Stderr, "overall, and with --large-mail, all mails above a size are listed.\n")
+		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "With --baseline, senders and partners whose mail count or volume deviates\n")
+		fmt.Fprintf(os.Stderr, "strongly from a previous period are reported instead, along with a score.\n")
+		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "With merge, JSON outputs of previous runs are merged instead of parsing\n")
+		fmt.Fprintf(os.Stderr, "logfiles. Mails contained in several files are only counted once.\n")
+		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "With diff, two periods are compared and new, disappeared and changed\n")
+		fmt.Fprintf(os.Stderr, "partners are reported. Both periods are given as a JSON output of a\n")
+		fmt.Fprintf(os.Stderr, "previous run (ending in .json or .json.gz) or as a single logfile.\n")
+		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "With --template, the output is rendered by a Go text/template instead.\n")
 		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "Regular output is printed to stdout, everything else is printed to stderr.\n")
 		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "Usage: %s [options] logfile...\n", path.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "       %s [options] merge datafile...\n", path.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "       %s [options] diff before after\n", path.Base(os.Args[0]))
 		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "Available options:\n")
 		pflag.PrintDefaults()
@@ -146,6 +272,209 @@ func parseCLIOptions() {
 	}
 	pflag.Parse()
 	config.LogFiles = pflag.Args()
+	if len(config.LogFiles) > 0 {
+		switch config.LogFiles[0] {
+		case "merge", "diff":
+			config.Command = config.LogFiles[0]
+			config.DataFiles = config.LogFiles[1:]
+			config.LogFiles = nil
+		}
+	}
+	if config.Normalize {
+		config.LowerDomains = true
+		config.LowerLocal = true
+		config.StripSubaddr = true
+		config.UnwrapAddrs = true
+	}
+	if config.Strict && !pflag.CommandLine.Changed("max-skip-ratio") {
+		config.MaxSkipRatio = 0
+	}
+	if config.JSONOutput {
+		config.OutputFormat = "json"
+	}
+	if config.HTMLOutput {
+		config.OutputFormat = "html"
+	}
+}
+
+// selectedReports returns the names of all reports selected on the command line.
+// Reports replace the regular output, so only one of them can be created at a time.
+func selectedReports() []string {
+	var reports []string
+	if config.Command == "diff" {
+		reports = append(reports, "Diff")
+	}
+	if config.TopLimit > 0 {
+		reports = append(reports, "Top report")
+	}
+	if len(config.BaselineFiles) > 0 {
+		reports = append(reports, "Anomaly detection")
+	}
+	if config.Threads {
+		reports = append(reports, "Thread report")
+	}
+	if config.ResponseTimes {
+		reports = append(reports, "Response time report")
+	}
+	if config.HoursReport {
+		reports = append(reports, "Working hours report")
+	}
+	if config.SizeReport {
+		reports = append(reports, "Size report")
+	}
+	if config.LargeMail != "" {
+		reports = append(reports, "Large mail report")
+	}
+	return reports
+}
+
+// parseSize returns the number of bytes of a size like "512", "100K", "10M" or "1G"; units are binary and may end in "iB" or "B".
+func parseSize(size string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(size))
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "B"), "I")
+	multiplier := int64(1)
+	if value != "" {
+		if exp := strings.IndexByte("KMGT", value[len(value)-1]); exp >= 0 {
+			value = value[:len(value)-1]
+			for i := 0; i <= exp; i++ {
+				multiplier = multiplier * 1024
+			}
+		}
+	}
+	number, numberErr := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if numberErr != nil || math.IsNaN(number) || math.IsInf(number, 0) || number < 0 {
+		return 0, fmt.Errorf("Invalid size <%s>", size)
+	}
+	bytes := number * float64(multiplier)
+	if bytes >= math.MaxInt64 {
+		return 0, fmt.Errorf("Size <%s> is too large", size)
+	}
+	return int64(bytes), nil
+}
+
+// validateCLIOptions checks the parsed CLI arguments for invalid values.
+func validateCLIOptions() error {
+	switch config.OutputFormat {
+	case "csv", "json", "table", "html", "dot", "gexf", "graphml", "elastic", "cef", "leef":
+	default:
+		return fmt.Errorf("Unknown output format <%s>", config.OutputFormat)
+	}
+	if config.ElasticURL != "" {
+		if config.OutputFormat != "elastic" {
+			return fmt.Errorf("Sending to a server is only supported for elastic output")
+		}
+		if config.OutfileName != "" {
+			return fmt.Errorf("Elastic output can either be sent to a server or written to an outfile")
+		}
+	}
+	location, locationErr := time.LoadLocation(config.TimeZone)
+	if locationErr != nil {
+		return fmt.Errorf("Unknown time zone <%s>", config.TimeZone)
+	}
+	config.Location = location
+	if config.SyslogAddress != "" {
+		if config.OutputFormat != "cef" && config.OutputFormat != "leef" {
+			return fmt.Errorf("Sending to a syslog collector is only supported for CEF and LEEF output")
+		}
+		if config.OutfileName != "" {
+			return fmt.Errorf("CEF and LEEF output can either be sent to a syslog collector or written to an outfile")
+		}
+	}
+	if config.MaxSkipRatio > 1 {
+		return fmt.Errorf("Maximum skip ratio must not be greater than 1")
+	}
+	if config.TopLimit < 0 {
+		return fmt.Errorf("Top report needs a positive number of entries")
+	}
+	switch config.SubjectMode {
+	case "keep", "redact", "hash":
+	default:
+		return fmt.Errorf("Subjects can only be kept, redacted or hashed, not <%s>", config.SubjectMode)
+	}
+	switch config.GraphNodes {
+	case "address", "domain":
+	default:
+		return fmt.Errorf("Graph nodes can only be address or domain, not <%s>", config.GraphNodes)
+	}
+	switch config.GraphWeight {
+	case "count", "size":
+	default:
+		return fmt.Errorf("Graph edges can only be weighted by count or size, not <%s>", config.GraphWeight)
+	}
+	if config.CSVFields != "" {
+		for _, field := range strings.Split(config.CSVFields, ",") {
+			if _, ok := (&sglog.MailPartner{}).Field(field); !ok {
+				return fmt.Errorf("Unknown field <%s>, available fields are %s", field, strings.Join(sglog.MailPartnerFields, ","))
+			}
+		}
+	}
+	switch config.SortBy {
+	case "partner", "count", "bytes":
+	default:
+		return fmt.Errorf("Partners can only be sorted by partner, count or bytes, not <%s>", config.SortBy)
+	}
+	reports := selectedReports()
+	if len(reports) > 1 {
+		return fmt.Errorf("%s cannot be combined with %s", reports[0], strings.ToLower(reports[1]))
+	}
+	if len(reports) == 1 {
+		switch config.OutputFormat {
+		case "csv", "json", "table":
+		default:
+			return fmt.Errorf("%s only supports CSV, JSON and table output", reports[0])
+		}
+	}
+	switch config.TopRankBy {
+	case "count", "size":
+	default:
+		return fmt.Errorf("Top report can only be ranked by count or size, not <%s>", config.TopRankBy)
+	}
+	switch config.TopType {
+	case "", "i2i", "i2e", "e2i", "e2e", "b2i", "b2e", "b2s", "s2i", "s2e", "s2s", "i2s", "e2s":
+	default:
+		return fmt.Errorf("Top report can only be limited to a type like i2e, not <%s>", config.TopType)
+	}
+	if config.TemplateFile != "" && len(reports) > 0 {
+		return fmt.Errorf("%s cannot be combined with a template", reports[0])
+	}
+	switch config.TemplateScope {
+	case "data", "partner", "mail":
+	default:
+		return fmt.Errorf("Templates can only be executed per data, partner or mail, not <%s>", config.TemplateScope)
+	}
+	if config.Threads && config.SubjectMode != "keep" {
+		return fmt.Errorf("Thread report needs the original subjects")
+	}
+	if config.LargeMail != "" {
+		size, sizeErr := parseSize(config.LargeMail)
+		if sizeErr != nil {
+			return sizeErr
+		}
+		config.LargeMailSize = size
+	}
+	switch config.BulkFilter {
+	case "include", "exclude", "only":
+	default:
+		return fmt.Errorf("Bulk mails can only be included, excluded or shown exclusively, not <%s>", config.BulkFilter)
+	}
+	if config.BulkMinRcpts < 1 {
+		return fmt.Errorf("Bulk classification needs a positive number of recipients")
+	}
+	if len(config.BaselineFiles) > 0 && config.AnomalyScore <= 0 {
+		return fmt.Errorf("Anomaly score must be positive")
+	}
+	if _, ok := anomalyBucketLayouts[config.AnomalyBucket]; !ok {
+		return fmt.Errorf("Anomalies can only be detected per day or hour, not <%s>", config.AnomalyBucket)
+	}
+	if config.Command == "diff" {
+		if len(config.DataFiles) != 2 {
+			return fmt.Errorf("Diff needs exactly two periods to compare")
+		}
+		if config.DiffThreshold < 0 {
+			return fmt.Errorf("Diff threshold must not be negative")
+		}
+	}
+	return nil
 }
 
 /*
@@ -178,19 +507,228 @@ func createTestData() (int, error) {
 	return 0, nil
 }
 
-// isValidEmail returns true if address is a valid e-mail address, else false.
-func isValidEmail(address string) bool {
-	return reValidEmail.MatchString(address)
+// parseLogFiles parses all logFiles and aggregates the parsed mails.
+// The returned exit code may be non-zero even if processing can continue, e.g. if a logfile could not be read completely. If an error is returned, processing must be aborted.
+func parseLogFiles(logFiles []string) (sglog.MailData, *sglog.ParseStats, int, error) {
+	var numCPUs int
+	var maxThreads int
+
+	if len(logFiles) == 0 {
+		return sglog.MailData{}, nil, errUsage, fmt.Errorf("At least one logfile is required.")
+	}
+
+	numCPUs = runtime.NumCPU()
+	maxThreads = numCPUs - config.SpareThreads
+	if maxThreads > numCPUs {
+		maxThreads = numCPUs
+	}
+	if maxThreads < 2 {
+		maxThreads = 2
+	}
+
+	parser, parserErr := sglog.NewParser(sglog.Config{
+		InternalHosts: config.InternalHosts,
+		SpecialUsers:  config.SpecialUsers,
+		LowerDomains:  config.LowerDomains,
+		LowerLocal:    config.LowerLocal,
+		StripSubaddr:  config.StripSubaddr,
+		UnwrapAddrs:   config.UnwrapAddrs,
+		KeepRejected:  config.RejectsFile != "",
+		Workers:       maxThreads - 1,
+		SliceSize:     config.SliceSize,
+	})
+	if parserErr != nil {
+		return sglog.MailData{}, nil, errUsage, parserErr
+	}
+	stats := parser.Stats()
+
+	exitCode := errSuccess
+	for _, logfile := range logFiles {
+		if readErr := parser.ReadFile(logfile); readErr != nil {
+			if config.Strict {
+				return sglog.MailData{}, stats, readErrorCode(readErr), readErr
+			}
+			stdErr.Println(readErr)
+			if exitCode == errSuccess {
+				exitCode = readErrorCode(readErr)
+			}
+		}
+	}
+	if stats.RelevantLines() == 0 {
+		if config.Strict {
+			exitCode = errNoMails
+		}
+		return sglog.MailData{}, stats, exitCode, fmt.Errorf("No relevant log lines found. Exiting.")
+	}
+
+	aggregator := sglog.NewAggregator()
+	aggregator.AddAll(parser.Mails())
+
+	printParseSummary(stats)
+	if skipErr := checkSkipRatio(stats); skipErr != nil {
+		if config.Strict {
+			return sglog.MailData{}, stats, errSkipRatio, skipErr
+		}
+		stdErr.Println(skipErr)
+		if exitCode == errSuccess {
+			exitCode = errSkipRatio
+		}
+	}
+	if config.RejectsFile != "" {
+		errCode, rejectsErr := writeRejectsFile(config.RejectsFile, stats.Rejected())
+		if rejectsErr != nil {
+			return sglog.MailData{}, stats, errCode, rejectsErr
+		}
+	}
+
+	if stats.ParsedMails() == 0 {
+		if config.Strict {
+			exitCode = errNoMails
+		}
+		return sglog.MailData{}, stats, exitCode, fmt.Errorf("No parsable log line found. Exiting.")
+	}
+	return aggregator.Data(), stats, exitCode, nil
+}
+
+// mergeDataFiles reads all dataFiles and merges their mails, skipping duplicates.
+// The returned exit code may be non-zero even if processing can continue, e.g. if a data file could not be read. If an error is returned, processing must be aborted.
+func mergeDataFiles(dataFiles []string) (sglog.MailData, int, error) {
+	var added, duplicates, files int64
+
+	if len(dataFiles) == 0 {
+		return sglog.MailData{}, errUsage, fmt.Errorf("At least one data file is required.")
+	}
+
+	aggregator := sglog.NewAggregator()
+	exitCode := errSuccess
+	for _, dataFile := range dataFiles {
+		md, errCode, readErr := readDataFile(dataFile)
+		if readErr != nil {
+			if config.Strict {
+				return sglog.MailData{}, errCode, readErr
+			}
+			stdErr.Println(readErr)
+			if exitCode == errSuccess {
+				exitCode = errCode
+			}
+			continue
+		}
+		fileAdded, fileDuplicates := aggregator.AddData(&md)
+		added = added + fileAdded
+		duplicates = duplicates + fileDuplicates
+		files++
+	}
+
+	stdErr.Printf("Merged %d mails from %d files, skipped %d duplicate mails.\n", added, files, duplicates)
+	if added == 0 {
+		return sglog.MailData{}, exitCode, fmt.Errorf("No mails found. Exiting.")
+	}
+	return aggregator.Data(), exitCode, nil
+}
+
+// loadDiffData loads both periods given on the command line for diff.
+// Each period is either a data file created with --format=json or a single logfile that is parsed.
+func loadDiffData() (sglog.MailData, sglog.MailData, int, error) {
+	var periods [2]sglog.MailData
+	exitCode := errSuccess
+	for i, fileName := range config.DataFiles {
+		var errCode int
+		var loadErr error
+		if strings.HasSuffix(fileName, ".json") || strings.HasSuffix(fileName, ".json.gz") {
+			periods[i], errCode, loadErr = readDataFile(fileName)
+		} else {
+			periods[i], _, errCode, loadErr = parseLogFiles([]string{fileName})
+		}
+		if loadErr != nil {
+			return periods[0], periods[1], errCode, loadErr
+		}
+		if exitCode == errSuccess {
+			exitCode = errCode
+		}
+	}
+	return periods[0], periods[1], exitCode, nil
+}
+
+// newCalendar creates the calendar for classifying mails as off-hours from the configured working hours, weekend and holiday files.
+func newCalendar() (*sglog.Calendar, int, error) {
+	calendar, calErr := sglog.NewCalendar(config.WorkHours, strings.Split(config.Weekend, ","))
+	if calErr != nil {
+		return nil, errUsage, calErr
+	}
+	for _, holidayFile := range config.HolidayFiles {
+		holidays, errCode, readErr := readHolidayFile(holidayFile)
+		if readErr != nil {
+			return nil, errCode, readErr
+		}
+		for _, holiday := range holidays {
+			if dayErr := calendar.AddHoliday(holiday); dayErr != nil {
+				return nil, errUsage, fmt.Errorf("Failed to read holidays from <%s>: %s", holidayFile, dayErr)
+			}
+		}
+	}
+	return calendar, errSuccess, nil
+}
+
+// classifyMails marks all mails in md sent by bulk senders or outside of working hours and applies the configured bulk filter.
+func classifyMails(md *sglog.MailData, calendar *sglog.Calendar) sglog.MailData {
+	var bulkMails int64
+	senders := sglog.BulkSenders(md, config.BulkMinRcpts)
+	classified := sglog.MailData{
+		CreateDateTime:     md.CreateDateTime,
+		CreateDateTimeUnix: md.CreateDateTimeUnix,
+		CreateDate:         md.CreateDate,
+		CreateTime:         md.CreateTime,
+	}
+	for _, partner := range md.Partner {
+		for _, mail := range partner.Mails {
+			_, isBulk := senders[mail.From]
+			mail.SetBulk(isBulk)
+			mail.SetOffHours(calendar.IsOffHours(mail.Date, mail.Time))
+			if isBulk {
+				bulkMails++
+			}
+			if (config.BulkFilter == "exclude" && isBulk) || (config.BulkFilter == "only" && !isBulk) {
+				continue
+			}
+			classified.Append(mail)
+		}
+	}
+	if config.BulkFilter != "include" {
+		stdErr.Printf("Classified %d senders with %d mails as bulk.\n", len(senders), bulkMails)
+	}
+	return classified
+}
+
+// readErrorCode returns the exit code matching an error returned by sglog.Parser.ReadFile.
+func readErrorCode(err error) int {
+	switch {
+	case errors.Is(err, sglog.ErrFileOpen):
+		return errFileOpen
+	case errors.Is(err, sglog.ErrGzipRead):
+		return errGzipRead
+	}
+	return errFileRead
+}
+
+// printParseSummary prints the number of parsed and skipped lines in stats to stderr.
+func printParseSummary(stats *sglog.ParseStats) {
+	stdErr.Printf("Parsed %d mails from %d relevant lines in %d files, skipped %d lines.\n", stats.ParsedMails(), stats.RelevantLines(), stats.Files(), stats.SkippedLines())
+	reasons, counts := stats.SkipReasons()
+	for i, reason := range reasons {
+		stdErr.Printf("Skipped %d lines: %s\n", counts[i], reason)
+	}
 }
 
-// waitAndClear completely fills and clears the thread management semaphore.
-func waitAndClear(threadMgmt *chan bool) {
-	for i := 0; i < cap(*threadMgmt); i++ {
-		*threadMgmt <- true
+// checkSkipRatio returns an error if the ratio of skipped to relevant lines in stats exceeds the configured maximum.
+func checkSkipRatio(stats *sglog.ParseStats) error {
+	if config.MaxSkipRatio < 0 || stats.RelevantLines() == 0 {
+		return nil
 	}
-	for i := 0; i < cap(*threadMgmt); i++ {
-		<-*threadMgmt
+	ratio := float64(stats.SkippedLines()) / float64(stats.RelevantLines())
+	if ratio > config.MaxSkipRatio {
+		return fmt.Errorf("Skipped %d of %d relevant lines (%.2f%%), which exceeds the maximum of %.2f%%", stats.SkippedLines(), stats.RelevantLines(), ratio*100, config.MaxSkipRatio*100)
 	}
+	return nil
 }
 
 /*
@@ -204,9 +742,6 @@ func waitAndClear(threadMgmt *chan bool) {
 */
 
 func main() {
-	var numCPUs int
-	var maxThreads int
-
 	parseCLIOptions()
 
 	if config.PrintVersion {
@@ -214,6 +749,11 @@ func main() {
 		os.Exit(errSuccess)
 	}
 
+	if optErr := validateCLIOptions(); optErr != nil {
+		stdErr.Printf("%s\n", optErr)
+		os.Exit(errUsage)
+	}
+
 	if config.CreateTestdata {
 		errCode, outErr := createTestData()
 		if outErr != nil {
@@ -223,87 +763,149 @@ func main() {
 		os.Exit(errSuccess)
 	}
 
-	if len(config.LogFiles) == 0 {
-		stdErr.Fatal("At least one logfile is required.")
+	calendar, errCode, calErr := newCalendar()
+	if calErr != nil {
+		stdErr.Println(calErr)
+		os.Exit(errCode)
 	}
 
-	numCPUs = runtime.NumCPU()
-	maxThreads = numCPUs - config.SpareThreads
-	if maxThreads > numCPUs {
-		maxThreads = numCPUs
-	}
-	if maxThreads < 2 {
-		maxThreads = 2
+	var tmpl *template.Template
+	if config.TemplateFile != "" {
+		var tmplErr error
+		if tmpl, tmplErr = loadTemplate(config.TemplateFile); tmplErr != nil {
+			stdErr.Println(tmplErr)
+			os.Exit(errUsage)
+		}
 	}
-	threadManager := make(chan bool, maxThreads-1)
 
-	if config.SliceSize < 10 {
-		config.SliceSize = 10
+	var mails, baseline sglog.MailData
+	var stats *sglog.ParseStats
+	var exitCode int
+	var inputErr error
+	switch config.Command {
+	case "merge":
+		mails, exitCode, inputErr = mergeDataFiles(config.DataFiles)
+	case "diff":
+		baseline, mails, exitCode, inputErr = loadDiffData()
+	default:
+		mails, stats, exitCode, inputErr = parseLogFiles(config.LogFiles)
 	}
-
-	mails.CreateDateTime = time.Now()
-	mails.CreateDateTimeUnix = mails.CreateDateTime.Unix()
-	mails.CreateDate = mails.CreateDateTime.Format("2006-01-02")
-	mails.CreateTime = mails.CreateDateTime.Format("15:04:05")
-
-	for _, logfile := range config.LogFiles {
-		parseErr := parseLogFile(logfile)
-		if parseErr != nil {
-			stdOut.Println(parseErr)
+	if inputErr == nil && len(config.BaselineFiles) > 0 {
+		baseline, errCode, inputErr = mergeDataFiles(config.BaselineFiles)
+		if exitCode == errSuccess || inputErr != nil {
+			exitCode = errCode
 		}
 	}
+	if inputErr != nil {
+		stdErr.Println(inputErr)
+		os.Exit(exitCode)
+	}
 
-	if lb.Len() > 0 {
-		for lb.Len() > 0 {
-			lines, linesErr := lb.PopSlice(config.SliceSize)
-			if linesErr != nil {
-				stdErr.Printf("Could not pop log lines: %s\n", linesErr)
-				continue
-			}
-			threadManager <- true
-			go parseLogLineSlice(&threadManager, lines)
-		}
-	} else {
-		stdErr.Println("No relevant log lines found. Exiting.")
-		os.Exit(errSuccess)
+	mails = classifyMails(&mails, calendar)
+	if config.Command == "diff" || len(config.BaselineFiles) > 0 {
+		baseline = classifyMails(&baseline, calendar)
 	}
-	waitAndClear(&threadManager)
 
-	if mb.Len() > 0 {
-		for mb.Len() > 0 {
-			mailSlice, mailSliceErr := mb.PopSlice(config.SliceSize)
-			if mailSliceErr != nil {
-				stdErr.Printf("Could nor pop mails: %s\n", mailSliceErr)
-				continue
-			}
-			for _, mail := range mailSlice {
-				mails.Append(mail)
-			}
+	if config.Pseudonymize || config.SubjectMode != "keep" {
+		ps, randomKey, psErr := newPseudonymizer(config.PseudoKeyFile, config.Pseudonymize, config.PseudoDomains, config.SubjectMode)
+		if psErr != nil {
+			stdErr.Printf("%s\n", psErr)
+			os.Exit(errUsage)
+		}
+		if randomKey && (config.Pseudonymize || config.SubjectMode == "hash") {
+			stdErr.Println("No pseudonymization key given, using a random key. Pseudonyms will differ between runs.")
+		}
+		mails = ps.Data(&mails)
+		if config.Command == "diff" || len(config.BaselineFiles) > 0 {
+			baseline = ps.Data(&baseline)
 		}
-	} else {
-		stdErr.Println("No parsable log line found. Exiting.")
-		os.Exit(errSuccess)
 	}
 
 	output := ""
-	if config.JSONOutput {
-		json, _ := json.MarshalIndent(mails, "", "    ")
-		output = string(json)
-	} else {
-		if !config.NoCSVHeader {
-			output = fmt.Sprintf("%s%s\n", output, mailPartnerCSVHeader)
-		}
-		var keys []string
-		for k := range mails.Partner {
-			keys = append(keys, k)
+	if config.Command == "diff" {
+		dr := newDiffReport(&baseline, &mails, config.DataFiles[0], config.DataFiles[1], config.DiffThreshold)
+		output = formatReport(&dr)
+	} else if len(config.BaselineFiles) > 0 {
+		ar := newAnomalyReport(&mails, &baseline, config.AnomalyBucket, config.AnomalyScore)
+		output = formatReport(&ar)
+	} else if tmpl != nil {
+		rendered, renderErr := formatTemplate(tmpl, &mails, config.TemplateScope)
+		if renderErr != nil {
+			stdErr.Printf("%s\n", renderErr)
+			os.Exit(errRender)
 		}
-		sort.Strings(keys)
-		for _, k := range keys {
-			mp := mails.Partner[k]
-			output = fmt.Sprintf("%s%s\n", output, mp.ToCSV())
+		output = rendered
+	} else if config.SizeReport {
+		sr := newSizeReport(&mails)
+		output = formatReport(&sr)
+	} else if config.LargeMail != "" {
+		lr := newLargeMailReport(&mails, config.LargeMailSize)
+		output = formatReport(&lr)
+	} else if config.HoursReport {
+		hr := newHoursReport(&mails)
+		output = formatReport(&hr)
+	} else if config.ResponseTimes {
+		rr := newResponseReport(&mails)
+		output = formatReport(&rr)
+	} else if config.Threads {
+		tr := newThreadReport(&mails)
+		output = formatReport(&tr)
+	} else if config.TopLimit > 0 {
+		tr := newTopReport(&mails, config.TopLimit, config.TopRankBy, config.TopType)
+		output = formatReport(&tr)
+	} else {
+		switch config.OutputFormat {
+		case "json":
+			output = formatJSON(mails)
+		case "table":
+			output = formatTable(&mails, stats)
+		case "html":
+			hr := newHTMLReport(&mails, stats, config.ReportTitle)
+			html, renderErr := hr.ToHTML()
+			if renderErr != nil {
+				stdErr.Printf("%s\n", renderErr)
+				os.Exit(errRender)
+			}
+			output = html
+		case "dot", "gexf", "graphml":
+			output = formatGraph(&mails)
+		case "elastic":
+			ee := newElasticExport(&mails, config.ElasticIndex, config.Location)
+			if config.ElasticURL != "" {
+				indexed, errCode, sendErr := sendElasticBatches(config.ElasticURL, ee.Batches(elasticBatchSize))
+				stdErr.Printf("Indexed %d of %d mails.\n", indexed, len(ee.Lines)/2)
+				if sendErr != nil {
+					stdErr.Printf("%s\n", sendErr)
+					os.Exit(errCode)
+				}
+				os.Exit(exitCode)
+			}
+			output = ee.ToNDJSON()
+		case "cef", "leef":
+			se := newSIEMExport(&mails, config.OutputFormat)
+			if config.SyslogAddress != "" {
+				sent, errCode, sendErr := sendSyslog(config.SyslogAddress, se.Events)
+				stdErr.Printf("Sent %d of %d events.\n", sent, len(se.Events))
+				if sendErr != nil {
+					stdErr.Printf("%s\n", sendErr)
+					os.Exit(errCode)
+				}
+				os.Exit(exitCode)
+			}
+			output = se.ToText()
+		default:
+			var fields []string
+			if config.CSVFields != "" {
+				fields = strings.Split(config.CSVFields, ",")
+			}
+			output = formatCSV(&mails, fields, !config.NoCSVHeader)
 		}
 	}
 	output = strings.TrimSpace(output)
+	if config.OutputFormat == "elastic" && output != "" {
+		// The _bulk API requires the request body to end with a newline.
+		output = output + "\n"
+	}
 
 	if config.OutfileName != "" {
 		var errCode int
@@ -321,5 +923,5 @@ func main() {
 		fmt.Print(output)
 	}
 
-	os.Exit(errSuccess)
+	os.Exit(exitCode)
 }
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gitlab.com/rbrt-weiler/sophos-sg-smtp-logparser/pkg/sglog"
+)
+
+// sortedPartnerKeys returns the keys of all mailPartners in md in alphabetical order.
+func sortedPartnerKeys(md *sglog.MailData) []string {
+	var keys []string
+	for k := range md.Partner {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedPartners returns all mailPartners of md ordered by their key.
+func sortedPartners(md *sglog.MailData) []sglog.MailPartner {
+	partners := make([]sglog.MailPartner, 0, len(md.Partner))
+	for _, key := range sortedPartnerKeys(md) {
+		partners = append(partners, md.Partner[key])
+	}
+	return partners
+}
+
+// sortedMails returns all mails of md ordered by date and time.
+func sortedMails(md *sglog.MailData) []sglog.SingleMail {
+	var mails []sglog.SingleMail
+	for _, partner := range md.Partner {
+		mails = append(mails, partner.Mails...)
+	}
+	sortMails(mails)
+	return mails
+}
+
+// sortMails orders mails by date and time, in place. Mails sent at the same time are ordered by mailID.
+func sortMails(mails []sglog.SingleMail) {
+	sort.SliceStable(mails, func(i, j int) bool {
+		a, b := mails[i], mails[j]
+		if a.Date+" "+a.Time != b.Date+" "+b.Time {
+			return a.Date+" "+a.Time < b.Date+" "+b.Time
+		}
+		return a.MailID < b.MailID
+	})
+}
+
+// formatJSON returns the indented JSON representation of v.
+func formatJSON(v interface{}) string {
+	json, _ := json.MarshalIndent(v, "", "    ")
+	return string(json)
+}
+
+// sortPartners orders partners by mail count or bytes (largest first) or by partner, in place.
+// Partners with equal count or bytes are ordered by partner.
+func sortPartners(partners []sglog.MailPartner, by string) {
+	sort.SliceStable(partners, func(i, j int) bool {
+		a, b := partners[i], partners[j]
+		switch {
+		case by == "count" && a.MailsTotal != b.MailsTotal:
+			return a.MailsTotal > b.MailsTotal
+		case (by == "bytes" || by == "size") && a.SizeTotal != b.SizeTotal:
+			return a.SizeTotal > b.SizeTotal
+		}
+		return a.PartnerA+" "+a.PartnerB < b.PartnerA+" "+b.PartnerB
+	})
+}
+
+// formatCSV returns a CSV representation of all mailPartners in md, ordered as configured with --sort.
+// If fields is empty, the columns of sglog.MailPartnerCSVHeader are written, else the given fields in the given order.
+func formatCSV(md *sglog.MailData, fields []string, withHeader bool) string {
+	var sb strings.Builder
+	if withHeader {
+		if len(fields) == 0 {
+			sb.WriteString(sglog.MailPartnerCSVHeader)
+		} else {
+			sb.WriteString(strings.Join(fields, ","))
+		}
+		sb.WriteString("\n")
+	}
+	partners := sortedPartners(md)
+	sortPartners(partners, config.SortBy)
+	for _, mp := range partners {
+		if len(fields) == 0 {
+			sb.WriteString(mp.ToCSV())
+		} else {
+			values := make([]string, len(fields))
+			for i, field := range fields {
+				value, _ := mp.Field(field)
+				values[i] = csvQuote(value)
+			}
+			sb.WriteString(strings.Join(values, ","))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// formatTable returns a human-readable summary of the mail traffic in md and the statistics in ps.
+// Colour is only used when output is written to a terminal.
+func formatTable(md *sglog.MailData, ps *sglog.ParseStats) string {
+	sr := newSummaryReport(md, ps)
+	return sr.ToTable(config.OutfileName == "" && isTerminal(os.Stdout))
+}
+
+// displayHost returns host, or address if host is empty as for sglog.NullSender.
+func displayHost(host string, address string) string {
+	if host == "" {
+		return address
+	}
+	return host
+}
+
+// formatBytes returns a human-friendly representation of size, for example "1.4 MiB".
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// formatCount returns count with thousands separators, for example "12,345".
+func formatCount(count int64) string {
+	digits := strconv.FormatInt(count, 10)
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	var sb strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			sb.WriteRune(',')
+		}
+		sb.WriteRune(digit)
+	}
+	return sign + sb.String()
+}
+
+// formatDuration returns a human-friendly representation of a duration given in seconds, for example "2h 05m".
+func formatDuration(seconds int64) string {
+	if seconds < 0 {
+		return "-" + formatDuration(-seconds)
+	}
+	switch {
+	case seconds < 60:
+		return fmt.Sprintf("%ds", seconds)
+	case seconds < 3600:
+		return fmt.Sprintf("%dm %02ds", seconds/60, seconds%60)
+	case seconds < 86400:
+		return fmt.Sprintf("%dh %02dm", seconds/3600, seconds%3600/60)
+	}
+	return fmt.Sprintf("%dd %02dh", seconds/86400, seconds%86400/3600)
+}
+
+// csvQuote returns value quoted for use in a CSV field if it contains commas, quotes or line breaks.
+func csvQuote(value string) string {
+	if !strings.ContainsAny(value, ",\"\r\n") {
+		return value
+	}
+	return "\"" + strings.ReplaceAll(value, "\"", "\"\"") + "\""
+}
+
+// isTerminal returns true if file is an interactive terminal and the NO_COLOR environment variable is not set, else false.
+func isTerminal(file *os.File) bool {
+	if _, noColour := os.LookupEnv("NO_COLOR"); noColour {
+		return false
+	}
+	info, infoErr := file.Stat()
+	if infoErr != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// formatGraph renders the communication graph of md in the configured graph format.
+func formatGraph(md *sglog.MailData) string {
+	cg := newCommGraph(md, config.GraphNodes == "domain", config.GraphWeight)
+	switch config.OutputFormat {
+	case "gexf":
+		return cg.ToGEXF()
+	case "graphml":
+		return cg.ToGraphML()
+	default:
+		return cg.ToDOT()
+	}
+}
+
+// formatReport renders r in the configured output format.
+func formatReport(r report) string {
+	switch config.OutputFormat {
+	case "json":
+		return formatJSON(r)
+	case "table":
+		return r.ToTable()
+	default:
+		return r.ToCSV(!config.NoCSVHeader)
+	}
+}
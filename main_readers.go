@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gitlab.com/rbrt-weiler/sophos-sg-smtp-logparser/pkg/sglog"
+)
+
+// readDataFile reads a JSON file created with --format=json into a MailData object.
+// Files with the extension .gz are decompressed, so files written with -Z can be read as well.
+func readDataFile(fileName string) (sglog.MailData, int, error) {
+	var md sglog.MailData
+	var reader io.Reader
+	var isGzip bool
+
+	file, fileErr := os.Open(fileName)
+	if fileErr != nil {
+		return md, errFileOpen, fmt.Errorf("Failed to open file: %s", fileErr)
+	}
+	defer file.Close()
+	reader = file
+
+	if strings.HasSuffix(fileName, ".gz") {
+		gz, gzErr := gzip.NewReader(file)
+		if gzErr != nil {
+			return md, errGzipRead, fmt.Errorf("Failed to open gzip'ed file <%s>: %s", fileName, gzErr)
+		}
+		reader = gz
+		isGzip = true
+	}
+
+	content, readErr := io.ReadAll(reader)
+	if readErr != nil {
+		if isGzip {
+			return md, errGzipRead, fmt.Errorf("Gzip'ed file <%s> is corrupt: %s", fileName, readErr)
+		}
+		return md, errFileRead, fmt.Errorf("Failed to read file <%s>: %s", fileName, readErr)
+	}
+	if jsonErr := json.Unmarshal(content, &md); jsonErr != nil {
+		return md, errDataDecode, fmt.Errorf("Failed to decode JSON data in <%s>: %s", fileName, jsonErr)
+	}
+	if checkErr := checkMailData(&md); checkErr != nil {
+		return sglog.MailData{}, errDataDecode, fmt.Errorf("Failed to decode JSON data in <%s>: %s", fileName, checkErr)
+	}
+	return md, errSuccess, nil
+}
+
+// checkMailData returns an error if a mail in md lacks one of the fields needed to aggregate it again.
+func checkMailData(md *sglog.MailData) error {
+	for _, partnerKey := range sortedPartnerKeys(md) {
+		for i, mail := range md.Partner[partnerKey].Mails {
+			switch {
+			case mail.From == "", mail.To == "":
+				return fmt.Errorf("Mail %d of partner <%s> has no from or to address", i+1, partnerKey)
+			case mail.TypeFrom == "", mail.TypeTo == "":
+				return fmt.Errorf("Mail %d of partner <%s> has no typeFrom or typeTo", i+1, partnerKey)
+			}
+		}
+	}
+	return nil
+}
+
+// readHolidayFile reads all holidays from fileName, one ISO date per line.
+// Empty lines and lines starting with # are ignored, as is everything after the date, so holidays can be annotated.
+func readHolidayFile(fileName string) ([]string, int, error) {
+	var holidays []string
+	file, fileErr := os.Open(fileName)
+	if fileErr != nil {
+		return holidays, errFileOpen, fmt.Errorf("Failed to open file: %s", fileErr)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		holidays = append(holidays, fields[0])
+	}
+	if scanErr := scanner.Err(); scanErr != nil {
+		return holidays, errFileRead, fmt.Errorf("Failed to read file <%s>: %s", fileName, scanErr)
+	}
+	return holidays, errSuccess, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"gitlab.com/rbrt-weiler/sophos-sg-smtp-logparser/pkg/sglog"
+)
+
+// loadTemplate parses the template file given with --template along with all helper functions.
+func loadTemplate(fileName string) (*template.Template, error) {
+	content, readErr := os.ReadFile(fileName)
+	if readErr != nil {
+		return nil, fmt.Errorf("Failed to read template: %s", readErr)
+	}
+	tmpl, parseErr := template.New(path.Base(fileName)).Funcs(templateFuncs()).Parse(string(content))
+	if parseErr != nil {
+		return nil, fmt.Errorf("Failed to parse template: %s", parseErr)
+	}
+	return tmpl, nil
+}
+
+// formatTemplate executes tmpl against md as a whole, once per mailPartner or once per singleMail, depending on scope.
+func formatTemplate(tmpl *template.Template, md *sglog.MailData, scope string) (string, error) {
+	var sb strings.Builder
+	var execErr error
+	switch scope {
+	case "partner":
+		for _, partner := range sortedPartners(md) {
+			if execErr = tmpl.Execute(&sb, partner); execErr != nil {
+				break
+			}
+		}
+	case "mail":
+		for _, mail := range sortedMails(md) {
+			if execErr = tmpl.Execute(&sb, mail); execErr != nil {
+				break
+			}
+		}
+	default:
+		execErr = tmpl.Execute(&sb, md)
+	}
+	if execErr != nil {
+		return "", fmt.Errorf("Failed to execute template: %s", execErr)
+	}
+	return sb.String(), nil
+}
+
+// templateFuncs returns the helper functions available in templates.
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"bytes": func(value interface{}) string {
+			return formatBytes(templateInt(value))
+		},
+		"count": func(value interface{}) string {
+			return formatCount(templateInt(value))
+		},
+		"date":         templateDate,
+		"csv":          func(value interface{}) string { return csvQuote(fmt.Sprint(value)) },
+		"json":         templateJSON,
+		"partners":     sortedPartners,
+		"sortPartners": templateSortPartners,
+		"sortMails":    templateSortMails,
+		"join":         func(separator string, values []string) string { return strings.Join(values, separator) },
+		"lower":        strings.ToLower,
+		"upper":        strings.ToUpper,
+	}
+}
+
+// templateInt converts the integer and float types used in templates to int64.
+func templateInt(value interface{}) int64 {
+	switch v := value.(type) {
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
+
+// templateDate formats a time.Time or a date and optional time as used in singleMail using a Go time layout like "02.01.2006 15:04".
+func templateDate(layout string, values ...interface{}) (string, error) {
+	if len(values) == 1 {
+		if t, ok := values[0].(time.Time); ok {
+			return t.Format(layout), nil
+		}
+	}
+	var parts []string
+	for _, value := range values {
+		parts = append(parts, fmt.Sprint(value))
+	}
+	joined := strings.Join(parts, " ")
+	for _, input := range []string{"2006-01-02 15:04:05", "2006-01-02"} {
+		if t, parseErr := time.Parse(input, joined); parseErr == nil {
+			return t.Format(layout), nil
+		}
+	}
+	return "", fmt.Errorf("cannot parse date <%s>", joined)
+}
+
+// templateJSON returns the JSON representation of value, e.g. a quoted and escaped string.
+func templateJSON(value interface{}) (string, error) {
+	encoded, jsonErr := json.Marshal(value)
+	return string(encoded), jsonErr
+}
+
+// templateSortPartners returns a copy of partners ordered by mail count or bytes (largest first) or by partner.
+func templateSortPartners(by string, partners []sglog.MailPartner) ([]sglog.MailPartner, error) {
+	switch by {
+	case "count", "bytes", "size", "partner":
+	default:
+		return nil, fmt.Errorf("partners can only be sorted by count, bytes or partner, not <%s>", by)
+	}
+	sorted := make([]sglog.MailPartner, len(partners))
+	copy(sorted, partners)
+	sortPartners(sorted, by)
+	return sorted, nil
+}
+
+// templateSortMails returns a copy of mails ordered by date and time.
+func templateSortMails(mails []sglog.SingleMail) []sglog.SingleMail {
+	sorted := make([]sglog.SingleMail, len(mails))
+	copy(sorted, mails)
+	sortMails(sorted)
+	return sorted
+}
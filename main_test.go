@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"gitlab.com/rbrt-weiler/sophos-sg-smtp-logparser/pkg/sglog"
+)
+
+// testMail returns a mail between two addresses, typed as internal for example.com and as external otherwise.
+func testMail(from string, to string, date string, clock string, subject string, size int64) sglog.SingleMail {
+	var mail sglog.SingleMail
+	mail.SetDate(date)
+	mail.SetTime(clock)
+	mail.SetFrom(from)
+	mail.SetTo(to)
+	typeOf := func(host string) string {
+		if host == "example.com" {
+			return "internal"
+		}
+		return "external"
+	}
+	mail.SetTypeFrom(typeOf(mail.HostFrom))
+	mail.SetTypeTo(typeOf(mail.HostTo))
+	mail.SetSubject(subject)
+	mail.Size = size
+	mail.SetQueueID(fmt.Sprintf("%s-%s-%s", date, clock, subject))
+	mail.GenerateMailID()
+	return mail
+}
+
+// testData returns the MailData of all mails, appended in the given order.
+func testData(mails ...sglog.SingleMail) sglog.MailData {
+	var md sglog.MailData
+	for _, mail := range mails {
+		md.Append(mail)
+	}
+	return md
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		size    string
+		want    int64
+		wantErr bool
+	}{
+		{"512", 512, false},
+		{" 100K ", 100 * 1024, false},
+		{"10M", 10 * 1024 * 1024, false},
+		{"10mb", 10 * 1024 * 1024, false},
+		{"1.5MiB", 1536 * 1024, false},
+		{"1G", 1 << 30, false},
+		{"2TB", 2 << 40, false},
+		{"0", 0, false},
+		{"", 0, true},
+		{"M", 0, true},
+		{"ten", 0, true},
+		{"-1K", 0, true},
+		{"NaN", 0, true},
+		{"Inf", 0, true},
+		{"-Inf", 0, true},
+		{"1e30", 0, true},
+		{"8388608T", 0, true},
+		{"8388607T", 8388607 << 40, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.size, func(t *testing.T) {
+			got, err := parseSize(tt.size)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("parseSize(%q) = %d, %v, want %d, error %v", tt.size, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
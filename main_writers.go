@@ -4,9 +4,18 @@ import (
 	"bufio"
 	"bytes"
 	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
+
+	"gitlab.com/rbrt-weiler/sophos-sg-smtp-logparser/pkg/sglog"
 )
 
 // writeOutfile writes content to fileName.
@@ -51,3 +60,98 @@ func writeCompressedOutfile(fileName string, content string) (int, error) {
 	}
 	return writeOutfile(fileName, buf.String())
 }
+
+// writeRejectsFile writes all rejected log lines as CSV to fileName.
+func writeRejectsFile(fileName string, rejected []sglog.RejectedLine) (int, error) {
+	var buf bytes.Buffer
+	csvWriter := csv.NewWriter(&buf)
+	csvWriter.Write([]string{"file", "line", "reason", "content"})
+	for _, reject := range rejected {
+		csvWriter.Write([]string{reject.Line.File(), strconv.FormatUint(uint64(reject.Line.Line()), 10), reject.Reason, reject.Line.String()})
+	}
+	csvWriter.Flush()
+	if csvErr := csvWriter.Error(); csvErr != nil {
+		return errFileWrite, fmt.Errorf("Could not write rejected lines: %s", csvErr)
+	}
+	return writeOutfile(fileName, buf.String())
+}
+
+// sendElasticBatches sends all batches to the _bulk API of the Elasticsearch or OpenSearch server at url.
+// Credentials can be given as part of url. The number of indexed mails is returned along with the exit code.
+func sendElasticBatches(url string, batches []string) (int64, int, error) {
+	var indexed int64
+	if !strings.HasSuffix(url, "/_bulk") {
+		url = strings.TrimSuffix(url, "/") + "/_bulk"
+	}
+	client := &http.Client{Timeout: 2 * time.Minute}
+	for _, batch := range batches {
+		response, postErr := client.Post(url, "application/x-ndjson", strings.NewReader(batch))
+		if postErr != nil {
+			return indexed, errSend, fmt.Errorf("Could not send mails: %s", postErr)
+		}
+		body, readErr := io.ReadAll(response.Body)
+		response.Body.Close()
+		if readErr != nil {
+			return indexed, errSend, fmt.Errorf("Could not read response: %s", readErr)
+		}
+		if response.StatusCode < 200 || response.StatusCode > 299 {
+			return indexed, errSend, fmt.Errorf("Server rejected mails with status %s: %s", response.Status, strings.TrimSpace(string(body)))
+		}
+		var result struct {
+			Errors bool `json:"errors"`
+			Items  []map[string]struct {
+				Status int `json:"status"`
+				Error  struct {
+					Type   string `json:"type"`
+					Reason string `json:"reason"`
+				} `json:"error"`
+			} `json:"items"`
+		}
+		if jsonErr := json.Unmarshal(body, &result); jsonErr != nil {
+			return indexed, errSend, fmt.Errorf("Could not decode response: %s", jsonErr)
+		}
+		for _, item := range result.Items {
+			for _, status := range item {
+				if status.Status >= 200 && status.Status <= 299 {
+					indexed++
+				} else if result.Errors {
+					return indexed, errSend, fmt.Errorf("Server rejected mail with status %d: %s: %s", status.Status, status.Error.Type, status.Error.Reason)
+				}
+			}
+		}
+	}
+	return indexed, errSuccess, nil
+}
+
+// sendSyslog sends every event as a syslog message (RFC 3164, facility local0, severity info) to the collector at address.
+// address is given as udp://host:port or tcp://host:port; without scheme, UDP is used, without port, port 514.
+// The number of sent events is returned along with the exit code.
+func sendSyslog(address string, events []string) (int, int, error) {
+	network := "udp"
+	if scheme, hostPort, found := strings.Cut(address, "://"); found {
+		network, address = strings.ToLower(scheme), hostPort
+	}
+	if _, _, splitErr := net.SplitHostPort(address); splitErr != nil {
+		address = net.JoinHostPort(strings.Trim(address, "[]"), "514")
+	}
+	conn, dialErr := net.DialTimeout(network, address, 30*time.Second)
+	if dialErr != nil {
+		return 0, errSend, fmt.Errorf("Could not connect to syslog collector: %s", dialErr)
+	}
+	defer conn.Close()
+	hostname, hostErr := os.Hostname()
+	if hostErr != nil || hostname == "" {
+		hostname = "-"
+	}
+	for sent, event := range events {
+		message := fmt.Sprintf("<134>%s %s ssslp: %s", time.Now().Format(time.Stamp), hostname, event)
+		if network == "tcp" {
+			message = message + "\n"
+		}
+		conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
+		if _, writeErr := conn.Write([]byte(message)); writeErr != nil {
+			return sent, errSend, fmt.Errorf("Could not send event to syslog collector: %s", writeErr)
+		}
+	}
+	return len(events), errSuccess, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSendElasticBatches(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		response    string
+		wantIndexed int64
+		wantCode    int
+		wantErr     string
+	}{
+		{"success", http.StatusOK, `{"errors":false,"items":[{"index":{"status":201}},{"index":{"status":200}}]}`, 4, errSuccess, ""},
+		{"rejected item", http.StatusOK, `{"errors":true,"items":[{"index":{"status":201}},{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse field [size]"}}}]}`, 1, errSend, "Server rejected mail with status 400: mapper_parsing_exception: failed to parse field [size]"},
+		{"server error", http.StatusServiceUnavailable, `{"error":"unavailable"}`, 0, errSend, "Server rejected mails with status 503 Service Unavailable"},
+		{"invalid response", http.StatusOK, `not json`, 0, errSend, "Could not decode response"},
+	}
+	batches := []string{"{\"index\":{}}\n{}\n{\"index\":{}}\n{}\n", "{\"index\":{}}\n{}\n{\"index\":{}}\n{}\n"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				body, _ := io.ReadAll(r.Body)
+				if r.Method != http.MethodPost || r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+					t.Errorf("request = %s %s (%s), want POST /_bulk (application/x-ndjson)", r.Method, r.URL.Path, r.Header.Get("Content-Type"))
+				}
+				if string(body) != batches[requests-1] {
+					t.Errorf("request body = %q, want %q", body, batches[requests-1])
+				}
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.response)
+			}))
+			defer server.Close()
+
+			indexed, code, err := sendElasticBatches(server.URL+"/", batches)
+			if indexed != tt.wantIndexed || code != tt.wantCode {
+				t.Errorf("sendElasticBatches() = %d, %d, want %d, %d", indexed, code, tt.wantIndexed, tt.wantCode)
+			}
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("sendElasticBatches() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.wantErr)) {
+				t.Errorf("sendElasticBatches() error = %v, want %q", err, tt.wantErr)
+			}
+			if tt.wantErr != "" && requests != 1 {
+				t.Errorf("sendElasticBatches() sent %d requests after an error, want 1", requests)
+			}
+		})
+	}
+}
+
+func TestSendElasticBatchesUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+	if _, code, err := sendElasticBatches(url, []string{"{}\n"}); code != errSend || err == nil {
+		t.Errorf("sendElasticBatches() = %d, %v, want %d and an error", code, err, errSend)
+	}
+}
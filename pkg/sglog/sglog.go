@@ -0,0 +1,119 @@
+// Package sglog parses SMTP logfiles of Sophos SG (formerly Astaro Security Gateway) into single mails and aggregates them by communication partners.
+//
+// A Parser reads logfiles and provides all parsed mails through a channel; an Aggregator combines them into MailData:
+//
+//	parser, err := sglog.NewParser(sglog.Config{InternalHosts: []string{"example.com"}})
+//	if err != nil {
+//		// handle invalid configuration
+//	}
+//	if err := parser.ReadFile("/var/log/smtp.log"); err != nil {
+//		// handle unreadable logfile
+//	}
+//	aggregator := sglog.NewAggregator()
+//	aggregator.AddAll(parser.Mails())
+//	data := aggregator.Data()
+//
+// The package does not use any package-level mutable state, so several Parsers can be used concurrently.
+package sglog
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	MaxLineLength int    = 1024 * 1024     // Maximum length of a single log line in bytes
+	NullSender    string = "MAILER-DAEMON" // Pseudo address used for mails with an empty sender, i.e. bounces
+)
+
+var (
+	// ErrFileOpen is matched by errors returned for logfiles that could not be opened.
+	ErrFileOpen = errors.New("logfile could not be opened")
+	// ErrFileRead is matched by errors returned for logfiles that could not be read completely.
+	ErrFileRead = errors.New("logfile could not be read completely")
+	// ErrGzipRead is matched by errors returned for corrupt gzip'ed logfiles.
+	ErrGzipRead = errors.New("gzip'ed logfile is corrupt")
+)
+
+const (
+	// Characters allowed in the local part of an e-mail address, apart from letters and digits.
+	emailLocalSpecials = "!#$%&'*+/=?^_`{|}~.-"
+)
+
+// IsRelevant returns true if line describes a mail passed by the SMTP proxy, else false.
+// Only relevant lines are parsed into SingleMail objects.
+func IsRelevant(line string) bool {
+	return strings.Contains(line, `smtpd[`) && strings.Contains(line, `name="email passed"`) && strings.Contains(line, `id="1000"`)
+}
+
+// splitAddress splits up the given e-mail address into user and host parts.
+// Addresses without host part, like NullSender, are returned as user with an empty host.
+func splitAddress(address string) (string, string) {
+	at := strings.LastIndexByte(address, '@')
+	if at < 0 {
+		return address, ""
+	}
+	return address[:at], address[at+1:]
+}
+
+// isValidEmail returns true if address is a valid e-mail address, else false.
+// The local part may consist of letters, digits, emailLocalSpecials and UTF-8 characters as allowed by SMTPUTF8. The host part must consist of valid DNS labels, either in ASCII or in Unicode form.
+func isValidEmail(address string) bool {
+	at := strings.LastIndexByte(address, '@')
+	if at < 1 {
+		return false
+	}
+	local := address[:at]
+	for i := 0; i < len(local); i++ {
+		c := local[i]
+		if !isASCIIAlnum(c) && c < 0x80 && strings.IndexByte(emailLocalSpecials, c) < 0 {
+			return false
+		}
+	}
+	if !isASCII(local) && !utf8.ValidString(local) {
+		return false
+	}
+	asciiHost, asciiErr := hostToASCII(address[at+1:])
+	if asciiErr != nil {
+		return false
+	}
+	return isValidHost(asciiHost)
+}
+
+// isValidHost returns true if host consists of valid DNS labels, else false.
+// Each label must start and end with a letter or digit and may contain hyphens in between.
+func isValidHost(host string) bool {
+	if host == "" {
+		return false
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" || len(label) > 63 {
+			return false
+		}
+		if !isASCIIAlnum(label[0]) || !isASCIIAlnum(label[len(label)-1]) {
+			return false
+		}
+		for i := 1; i < len(label)-1; i++ {
+			if !isASCIIAlnum(label[i]) && label[i] != '-' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isASCIIAlnum returns true if c is an ASCII letter or digit, else false.
+func isASCIIAlnum(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// waitAndClear completely fills and clears the thread management semaphore.
+func waitAndClear(threadMgmt *chan bool) {
+	for i := 0; i < cap(*threadMgmt); i++ {
+		*threadMgmt <- true
+	}
+	for i := 0; i < cap(*threadMgmt); i++ {
+		<-*threadMgmt
+	}
+}
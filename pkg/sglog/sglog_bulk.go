@@ -0,0 +1,152 @@
+package sglog
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	BulkLocalPart  string = "local-part" // Signal for senders with a local part like noreply or bounce
+	BulkSubjects   string = "subjects"   // Signal for senders that sent the same subject to many recipients
+	BulkRecipients string = "recipients" // Signal for senders with many recipients that hardly ever reply
+	BulkIntervals  string = "intervals"  // Signal for senders that send mails at regular intervals
+
+	bulkMinSends        int     = 5                     // Minimum number of distinct send times for BulkIntervals
+	bulkMinInterval     float64 = 60                    // Minimum mean interval in seconds for BulkIntervals
+	bulkMaxDeviation    float64 = 0.1                   // Maximum standard deviation of intervals relative to their mean for BulkIntervals
+	bulkMaxReplyRatio   float64 = 0.1                   // Maximum ratio of recipients that replied for BulkRecipients
+	bulkTimestampLayout string  = "2006-01-02 15:04:05" // Layout of the date and time of a SingleMail
+)
+
+var (
+	// Substrings of local parts used by automated senders
+	bulkLocalPartMarkers = []string{"noreply", "no-reply", "no_reply", "donotreply", "do-not-reply", "do_not_reply"}
+	// Leading words of local parts used by automated senders; they must be followed by a separator like "." or "-", a digit or nothing at all
+	bulkLocalPartWords = []string{"bounce", "bounces", "mailer-daemon", "news", "newsletter", "newsletters", "notification", "notifications", "notify"}
+)
+
+// Stores the behaviour of a single sender.
+type bulkSender struct {
+	recipients map[string]bool
+	replies    map[string]bool
+	subjects   map[string]map[string]bool
+	sendTimes  map[string]bool
+}
+
+// BulkSenders classifies all senders in md as bulk or automated senders based on their behaviour.
+// A sender is classified as bulk if its local part is typical for automated mail, if it sent the same subject to at least minRecipients recipients,
+// if it sent mails at regular intervals or if it sent mails to at least minRecipients recipients of which hardly anyone replied.
+// Bounces sent by NullSender are never classified as bulk.
+// The returned map contains the signals for every bulk sender; senders that are no bulk senders are not contained.
+func BulkSenders(md *MailData, minRecipients int) map[string][]string {
+	senders := make(map[string]*bulkSender)
+	get := func(address string) *bulkSender {
+		if _, ok := senders[address]; !ok {
+			senders[address] = &bulkSender{recipients: make(map[string]bool), replies: make(map[string]bool), subjects: make(map[string]map[string]bool), sendTimes: make(map[string]bool)}
+		}
+		return senders[address]
+	}
+	for _, partner := range md.Partner {
+		for _, mail := range partner.Mails {
+			sender := get(mail.From)
+			sender.recipients[mail.To] = true
+			sender.sendTimes[mail.Date+" "+mail.Time] = true
+			if mail.Subject != "" {
+				if _, ok := sender.subjects[mail.Subject]; !ok {
+					sender.subjects[mail.Subject] = make(map[string]bool)
+				}
+				sender.subjects[mail.Subject][mail.To] = true
+			}
+			get(mail.To).replies[mail.From] = true
+		}
+	}
+
+	bulk := make(map[string][]string)
+	for address, sender := range senders {
+		if address == NullSender || len(sender.sendTimes) == 0 {
+			continue
+		}
+		var signals []string
+		user, _ := splitAddress(address)
+		if isBulkLocalPart(user) {
+			signals = append(signals, BulkLocalPart)
+		}
+		for _, recipients := range sender.subjects {
+			if len(recipients) >= minRecipients {
+				signals = append(signals, BulkSubjects)
+				break
+			}
+		}
+		if len(sender.recipients) >= minRecipients {
+			var replied int
+			for recipient := range sender.recipients {
+				if sender.replies[recipient] {
+					replied++
+				}
+			}
+			if float64(replied) <= float64(len(sender.recipients))*bulkMaxReplyRatio {
+				signals = append(signals, BulkRecipients)
+			}
+		}
+		if hasRegularIntervals(sender.sendTimes) {
+			signals = append(signals, BulkIntervals)
+		}
+		if len(signals) > 0 {
+			bulk[address] = signals
+		}
+	}
+	return bulk
+}
+
+// isBulkLocalPart returns true if user is a local part typically used for automated mail.
+func isBulkLocalPart(user string) bool {
+	user = strings.ToLower(user)
+	for _, marker := range bulkLocalPartMarkers {
+		if strings.Contains(user, marker) {
+			return true
+		}
+	}
+	for _, word := range bulkLocalPartWords {
+		if !strings.HasPrefix(user, word) {
+			continue
+		}
+		if rest := user[len(word):]; rest == "" || strings.IndexByte(".-_+=0123456789", rest[0]) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// hasRegularIntervals returns true if the timestamps in sendTimes are spaced at nearly identical intervals.
+func hasRegularIntervals(sendTimes map[string]bool) bool {
+	if len(sendTimes) < bulkMinSends {
+		return false
+	}
+	var times []time.Time
+	for sendTime := range sendTimes {
+		if parsed, parseErr := time.Parse(bulkTimestampLayout, sendTime); parseErr == nil {
+			times = append(times, parsed)
+		}
+	}
+	if len(times) < bulkMinSends {
+		return false
+	}
+	sort.Slice(times, func(i, j int) bool {
+		return times[i].Before(times[j])
+	})
+	var sum, squares float64
+	for i := 1; i < len(times); i++ {
+		interval := times[i].Sub(times[i-1]).Seconds()
+		sum = sum + interval
+		squares = squares + interval*interval
+	}
+	count := float64(len(times) - 1)
+	mean := sum / count
+	if mean < bulkMinInterval {
+		return false
+	}
+	deviation := math.Sqrt(math.Max(squares/count-mean*mean, 0))
+	return deviation <= mean*bulkMaxDeviation
+}
@@ -0,0 +1,71 @@
+package sglog
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestIsBulkLocalPart(t *testing.T) {
+	tests := []struct {
+		user string
+		want bool
+	}{
+		{"noreply", true},
+		{"No-Reply", true},
+		{"shop-donotreply", true},
+		{"bounces+1234", true},
+		{"mailer-daemon", true},
+		{"newsletter", true},
+		{"news", true},
+		{"news.daily", true},
+		{"newsletter2020", true},
+		{"notifications", true},
+		{"notify-it", true},
+		{"john.doe", false},
+		{"newsome", false},
+		{"newsom", false},
+		{"bouncer", false},
+		{"reply", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.user, func(t *testing.T) {
+			if got := isBulkLocalPart(tt.user); got != tt.want {
+				t.Errorf("isBulkLocalPart(%q) = %v, want %v", tt.user, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBulkSenders(t *testing.T) {
+	var md MailData
+	// A shop sending the same subject to many recipients who never reply
+	for i, clock := range []string{"10:00:17", "10:03:05", "10:04:59", "10:11:00", "10:12:30"} {
+		md.Append(testMail("offers@shop.example.net", fmt.Sprintf("user%d@example.com", i), "2020-07-18", clock, "Summer sale", 10))
+	}
+	// A monitoring system reporting every hour to a single recipient
+	for i := 0; i < 5; i++ {
+		md.Append(testMail("monitor@else.example.org", "admin@example.com", "2020-07-18", fmt.Sprintf("%02d:00:00", 10+i), fmt.Sprintf("Status %d", i), 10))
+	}
+	// A noreply sender with a single mail
+	md.Append(testMail("noreply@else.example.org", "admin@example.com", "2020-07-18", "09:00:00", "Your order", 10))
+	// A bounce storm that must not be classified as bulk
+	for i, clock := range []string{"10:00:20", "10:03:09", "10:05:01", "10:11:04", "10:12:33"} {
+		md.Append(testMail(NullSender, fmt.Sprintf("offers@shop%d.example.net", i), "2020-07-18", clock, "Undeliverable: Summer sale", 10))
+	}
+	// Colleagues writing to each other
+	for i, clock := range []string{"10:30:00", "10:41:00", "13:02:00", "13:05:00", "16:48:00"} {
+		md.Append(testMail("alice@example.com", fmt.Sprintf("user%d@example.com", i), "2020-07-18", clock, fmt.Sprintf("Question %d", i), 10))
+		md.Append(testMail(fmt.Sprintf("user%d@example.com", i), "alice@example.com", "2020-07-18", clock[:3]+"59:00", fmt.Sprintf("Re: Question %d", i), 10))
+	}
+
+	got := BulkSenders(&md, 5)
+	want := map[string][]string{
+		"offers@shop.example.net":  {BulkSubjects, BulkRecipients},
+		"monitor@else.example.org": {BulkIntervals},
+		"noreply@else.example.org": {BulkLocalPart},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BulkSenders() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,100 @@
+package sglog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+)
+
+var (
+	// Decoder for MIME encoded-words as defined by RFC 2047.
+	subjectDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+	// Code points of windows-1252 for the bytes 0x80 to 0x9F; all other bytes match ISO-8859-1.
+	windows1252Table = [32]rune{
+		'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '\u008D', 'Ž', '\u008F',
+		'\u0090', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '\u009D', 'ž', 'Ÿ',
+	}
+
+	// Code points of ISO-8859-15 that differ from ISO-8859-1.
+	iso885915Table = map[byte]rune{
+		0xA4: '€', 0xA6: 'Š', 0xA8: 'š', 0xB4: 'Ž', 0xB8: 'ž', 0xBC: 'Œ', 0xBD: 'œ', 0xBE: 'Ÿ',
+	}
+)
+
+// charsetReader converts input in the given charset to UTF-8.
+// Apart from the charsets supported by mime.WordDecoder itself, windows-1252 and ISO-8859-15 are supported.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	var table func(b byte) rune
+	switch strings.ToLower(charset) {
+	case "windows-1252", "cp1252":
+		table = func(b byte) rune {
+			if b >= 0x80 && b <= 0x9F {
+				return windows1252Table[b-0x80]
+			}
+			return rune(b)
+		}
+	case "iso-8859-15", "latin-9", "latin9":
+		table = func(b byte) rune {
+			if r, ok := iso885915Table[b]; ok {
+				return r
+			}
+			return rune(b)
+		}
+	case "latin1", "latin-1", "iso8859-1", "iso_8859-1":
+		table = func(b byte) rune {
+			return rune(b)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported charset <%s>", charset)
+	}
+	content, readErr := io.ReadAll(input)
+	if readErr != nil {
+		return nil, readErr
+	}
+	var buf bytes.Buffer
+	for _, b := range content {
+		buf.WriteRune(table(b))
+	}
+	return &buf, nil
+}
+
+// decodeSubject decodes all MIME encoded-words (RFC 2047) contained in subject into UTF-8.
+// If subject cannot be decoded, it is returned unchanged.
+func decodeSubject(subject string) string {
+	if !strings.Contains(subject, "=?") {
+		return subject
+	}
+	decoded, decodeErr := subjectDecoder.DecodeHeader(subject)
+	if decodeErr != nil {
+		return subject
+	}
+	return decoded
+}
+
+// unescapeValue removes the backslash escaping the SG applies to quotes and backslashes in quoted values.
+func unescapeValue(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+	var sb strings.Builder
+	escaped := false
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if !escaped && c == '\\' {
+			escaped = true
+			continue
+		}
+		if escaped && c != '"' && c != '\\' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(c)
+		escaped = false
+	}
+	if escaped {
+		sb.WriteByte('\\')
+	}
+	return sb.String()
+}
@@ -0,0 +1,67 @@
+package sglog
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+const (
+	idnaACEPrefix string = "xn--" // Prefix of DNS labels encoded with Punycode
+)
+
+// isASCII returns true if s consists of ASCII characters only, else false.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// hostToASCII converts an internationalized host name into its ASCII form by encoding every non-ASCII label with Punycode.
+// Non-ASCII labels are mapped and normalized as defined by UTS 46 before encoding, ASCII labels are kept unchanged.
+func hostToASCII(host string) (string, error) {
+	if isASCII(host) {
+		return host, nil
+	}
+	if !utf8.ValidString(host) {
+		return "", fmt.Errorf("Host <%s> is not valid UTF-8", host)
+	}
+	labels := strings.Split(host, ".")
+	for i, label := range labels {
+		if isASCII(label) {
+			continue
+		}
+		encoded, encodeErr := idna.Lookup.ToASCII(label)
+		if encodeErr != nil {
+			return "", fmt.Errorf("Host <%s> is not a valid internationalized host name: %s", host, encodeErr)
+		}
+		labels[i] = encoded
+	}
+	return strings.Join(labels, "."), nil
+}
+
+// hostToUnicode converts a host name into its Unicode form by decoding every Punycode label.
+// Non-ASCII labels are mapped and normalized as defined by UTS 46, so all spellings of a label result in the same form.
+// Labels that cannot be converted are kept unchanged, as are ASCII labels.
+func hostToUnicode(host string) string {
+	if isASCII(host) && !strings.Contains(strings.ToLower(host), idnaACEPrefix) {
+		return host
+	}
+	labels := strings.Split(host, ".")
+	for i, label := range labels {
+		isACE := len(label) > len(idnaACEPrefix) && strings.EqualFold(label[:len(idnaACEPrefix)], idnaACEPrefix)
+		if isASCII(label) && !isACE {
+			continue
+		}
+		// Punycode labels must decode into non-ASCII characters, which is not checked by idna.
+		if decoded, decodeErr := idna.Lookup.ToUnicode(label); decodeErr == nil && (!isACE || !isASCII(decoded)) {
+			labels[i] = decoded
+		}
+	}
+	return strings.Join(labels, ".")
+}
@@ -0,0 +1,67 @@
+package sglog
+
+import "testing"
+
+func TestHostToASCII(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    string
+		want    string
+		wantErr bool
+	}{
+		// Sample strings of RFC 3492, section 7.1, in lower case as mapped by UTS 46
+		{"RFC 3492 (B) Chinese simplified", "他们为什么不说中文", "xn--ihqwcrb4cv8a8dqg056pqjye", false},
+		{"RFC 3492 (C) Chinese traditional", "他們爲什麽不說中文", "xn--ihqwctvzc91f659drss3x8bo0yb", false},
+		{"RFC 3492 (E) Hebrew", "למההםפשוטלאמדבריםעברית", "xn--4dbcagdahymbxekheh6e0a7fei0b", false},
+		{"RFC 3492 (I) Russian", "почемужеонинеговорятпорусски", "xn--b1abfaaepdrnnbgefbadotcwatmq2g4l", false},
+		{"RFC 3492 (K) Spanish", "porquénopuedensimplementehablarenespañol", "xn--porqunopuedensimplementehablarenespaol-fmd56a", false},
+		{"RFC 3492 (L) Japanese", "3年b組金八先生", "xn--3b-ww4c5e180e575a65lsy2b", false},
+		// Mapping and normalization of UTS 46
+		{"ASCII host", "Mail.Example.COM", "Mail.Example.COM", false},
+		{"composed", "bücher.de", "xn--bcher-kva.de", false},
+		{"decomposed (NFD)", "bu\u0308cher.de", "xn--bcher-kva.de", false},
+		{"upper case", "BÜCHER.de", "xn--bcher-kva.de", false},
+		{"full width", "ｅｘａｍｐｌｅ.com", "example.com", false},
+		{"ideographic full stop", "bücher。de", "xn--bcher-kva.de", false},
+		{"sharp s (nontransitional)", "faß.de", "xn--fa-hia.de", false},
+		{"disallowed space", "bü cher.de", "", true},
+		{"invalid UTF-8", "b\xfccher.de", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := hostToASCII(tt.host)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("hostToASCII(%q) error = %v, wantErr %v", tt.host, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("hostToASCII(%q) = %q, want %q", tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHostToUnicode(t *testing.T) {
+	tests := []struct {
+		name string
+		host string
+		want string
+	}{
+		{"RFC 3492 (B) Chinese simplified", "xn--ihqwcrb4cv8a8dqg056pqjye.cn", "他们为什么不说中文.cn"},
+		{"RFC 3492 (I) Russian", "xn--b1abfaaepdrnnbgefbadotcwatmq2g4l.ru", "почемужеонинеговорятпорусски.ru"},
+		{"RFC 3492 (L) Japanese", "xn--3b-ww4c5e180e575a65lsy2b.jp", "3年b組金八先生.jp"},
+		{"ASCII host", "Mail.Example.COM", "Mail.Example.COM"},
+		{"upper case prefix", "XN--BCHER-KVA.Example.COM", "bücher.Example.COM"},
+		{"decomposed (NFD)", "bu\u0308cher.de", "bücher.de"},
+		{"upper case", "BÜCHER.de", "bücher.de"},
+		{"invalid Punycode", "xn--99999999999.de", "xn--99999999999.de"},
+		{"Punycode without non-ASCII", "xn--bcher-.de", "xn--bcher-.de"},
+		{"disallowed space", "bü cher.de", "bü cher.de"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hostToUnicode(tt.host); got != tt.want {
+				t.Errorf("hostToUnicode(%q) = %q, want %q", tt.host, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package sglog
+
+import (
+	"strings"
+)
+
+var (
+	// Reply and forward prefixes removed by NormalizeSubject, in lower case and without the trailing colon
+	subjectPrefixes = map[string]bool{"re": true, "aw": true, "antw": true, "sv": true, "fw": true, "fwd": true, "wg": true, "tr": true}
+)
+
+// normalizeAddress applies all normalizations enabled in config to an e-mail address.
+// Rewritten addresses are unwrapped first, then subaddress tags are stripped and finally the address is converted to lower case.
+// Internationalized domains are always converted into their Unicode form. If the result is no valid e-mail address, address is returned unchanged.
+func normalizeAddress(config *Config, address string) string {
+	user, host := splitAddress(address)
+	if host == "" {
+		return address
+	}
+	if config.UnwrapAddrs {
+		user, host = unwrapAddress(user, host)
+	}
+	host = hostToUnicode(host)
+	if config.StripSubaddr {
+		user = stripSubaddress(user)
+	}
+	if config.LowerLocal {
+		user = strings.ToLower(user)
+	}
+	if config.LowerDomains {
+		host = strings.ToLower(host)
+	}
+	normalized := user + "@" + host
+	if !isValidEmail(normalized) {
+		return address
+	}
+	return normalized
+}
+
+// stripSubaddress removes a subaddress tag like "+tag" from the local part of an e-mail address.
+// Local parts that would be empty afterwards are returned unchanged.
+func stripSubaddress(user string) string {
+	if separator := strings.IndexByte(user, '+'); separator > 0 {
+		return user[:separator]
+	}
+	return user
+}
+
+// unwrapAddress restores the original address from an address rewritten by SRS (SRS0 and SRS1) or BATV (prvs and msprvs1).
+// Addresses that are not rewritten are returned unchanged.
+func unwrapAddress(user string, host string) (string, string) {
+	lowerUser := strings.ToLower(user)
+	switch {
+	case strings.HasPrefix(lowerUser, "srs0") && len(user) > 5:
+		// SRS0=hash=timestamp=domain=local@forwarder
+		if origUser, origHost, ok := unwrapSRS0(user[5:]); ok {
+			return origUser, origHost
+		}
+	case strings.HasPrefix(lowerUser, "srs1") && len(user) > 5:
+		// SRS1=hash=forwarder==hash=timestamp=domain=local@forwarder
+		parts := strings.SplitN(user[5:], "=", 3)
+		if len(parts) == 3 && len(parts[2]) > 1 {
+			if origUser, origHost, ok := unwrapSRS0(parts[2][1:]); ok {
+				return origUser, origHost
+			}
+		}
+	case strings.HasPrefix(lowerUser, "prvs="), strings.HasPrefix(lowerUser, "msprvs1="):
+		// prvs=tag=local@domain
+		parts := strings.SplitN(user, "=", 3)
+		if len(parts) == 3 && parts[2] != "" {
+			return parts[2], host
+		}
+	}
+	return user, host
+}
+
+// unwrapSRS0 extracts local part and domain from the part of an SRS0 address that follows "SRS0=".
+// The returned bool is false if the address does not follow the SRS0 format.
+func unwrapSRS0(rewritten string) (string, string, bool) {
+	parts := strings.SplitN(rewritten, "=", 4)
+	if len(parts) != 4 || parts[2] == "" || parts[3] == "" {
+		return "", "", false
+	}
+	return parts[3], parts[2], true
+}
+
+// NormalizeSubject removes reply and forward prefixes like "Re:", "AW:", "Fwd:" or "WG:" as well as tags in brackets like "[EXTERNAL]" or mailing list tags from the start of subject.
+// Numbered prefixes like "Re[2]:" are removed as well and whitespace is collapsed, so all mails of a conversation share the same normalized subject.
+func NormalizeSubject(subject string) string {
+	subject = strings.Join(strings.Fields(subject), " ")
+	for {
+		if strings.HasPrefix(subject, "[") {
+			end := strings.IndexByte(subject, ']')
+			if end < 0 {
+				break
+			}
+			subject = strings.TrimSpace(subject[end+1:])
+			continue
+		}
+		colon := strings.IndexByte(subject, ':')
+		if colon < 1 {
+			break
+		}
+		prefix := strings.ToLower(subject[:colon])
+		if counter := strings.IndexAny(prefix, "[("); counter > 0 && strings.Trim(prefix[counter:], "[]()0123456789") == "" {
+			prefix = prefix[:counter]
+		}
+		if !subjectPrefixes[strings.TrimSpace(prefix)] {
+			break
+		}
+		subject = strings.TrimSpace(subject[colon+1:])
+	}
+	return subject
+}
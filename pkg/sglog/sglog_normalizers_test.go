@@ -0,0 +1,61 @@
+package sglog
+
+import "testing"
+
+func TestNormalizeAddress(t *testing.T) {
+	all := Config{LowerDomains: true, LowerLocal: true, StripSubaddr: true, UnwrapAddrs: true}
+	tests := []struct {
+		name    string
+		config  Config
+		address string
+		want    string
+	}{
+		{"unchanged by default", Config{}, "John.Doe+news@Example.COM", "John.Doe+news@Example.COM"},
+		{"lower domains", Config{LowerDomains: true}, "John.Doe@Example.COM", "John.Doe@example.com"},
+		{"lower local parts", Config{LowerLocal: true}, "John.Doe@Example.COM", "john.doe@Example.COM"},
+		{"strip subaddress", Config{StripSubaddr: true}, "john+news@example.com", "john@example.com"},
+		{"strip subaddress keeps leading plus", Config{StripSubaddr: true}, "+john@example.com", "+john@example.com"},
+		{"unwrap SRS0", Config{UnwrapAddrs: true}, "SRS0=HHH=TT=example.org=bob@fwd.example.net", "bob@example.org"},
+		{"unwrap SRS1", Config{UnwrapAddrs: true}, "SRS1=HHH=fwd1.example.net==HHH=TT=example.org=bob@fwd2.example.net", "bob@example.org"},
+		{"unwrap BATV prvs", Config{UnwrapAddrs: true}, "prvs=1234abcd=bob@example.org", "bob@example.org"},
+		{"unwrap BATV msprvs1", Config{UnwrapAddrs: true}, "msprvs1=17abc=bob@example.org", "bob@example.org"},
+		{"malformed SRS0 is kept", Config{UnwrapAddrs: true}, "SRS0=HHH=bob@fwd.example.net", "SRS0=HHH=bob@fwd.example.net"},
+		{"SRS0 with invalid domain is kept", Config{UnwrapAddrs: true}, "SRS0=HHH=TT=exa_mple=bob@fwd.example.net", "SRS0=HHH=TT=exa_mple=bob@fwd.example.net"},
+		{"all normalizations", all, "SRS0=HHH=TT=Example.org=Bob+List@fwd.example.net", "bob@example.org"},
+		{"Punycode domain", Config{}, "user@xn--bcher-kva.de", "user@bücher.de"},
+		{"null sender", all, NullSender, NullSender},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeAddress(&tt.config, tt.address); got != tt.want {
+				t.Errorf("normalizeAddress(%q) = %q, want %q", tt.address, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeSubject(t *testing.T) {
+	tests := []struct {
+		subject string
+		want    string
+	}{
+		{"Quarterly report", "Quarterly report"},
+		{"Re: Quarterly report", "Quarterly report"},
+		{"RE: AW: Fwd: Quarterly report", "Quarterly report"},
+		{"Re[2]: Quarterly report", "Quarterly report"},
+		{"Re(3): Quarterly report", "Quarterly report"},
+		{"[EXTERNAL] WG: Quarterly report", "Quarterly report"},
+		{"[list] Re: [EXTERNAL]  Quarterly   report ", "Quarterly report"},
+		{"Meeting: Quarterly report", "Meeting: Quarterly report"},
+		{"Re:", ""},
+		{"[unterminated Quarterly report", "[unterminated Quarterly report"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.subject, func(t *testing.T) {
+			if got := NormalizeSubject(tt.subject); got != tt.want {
+				t.Errorf("NormalizeSubject(%q) = %q, want %q", tt.subject, got, tt.want)
+			}
+		})
+	}
+}
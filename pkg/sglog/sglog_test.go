@@ -0,0 +1,76 @@
+package sglog
+
+import "testing"
+
+func TestIsRelevant(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want bool
+	}{
+		{"email passed", `2020:07:18-16:56:31 some-sg smtpd[14020]: SCANNER[14020]: id="1000" severity="info" sys="SecureMail" sub="smtp" name="email passed" from="a@b.c"`, true},
+		{"other event", `2020:07:18-16:56:31 some-sg smtpd[14020]: SCANNER[14020]: id="1001" severity="info" sys="SecureMail" sub="smtp" name="email rejected"`, false},
+		{"other process", `2020:07:18-16:56:31 some-sg exim-in[24020]: id="1000" name="email passed"`, false},
+		{"queue manager", `2020:07:18-16:56:31 some-sg smtpd[4020]: QMGR[4020]: logfoo moved to work queue`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRelevant(tt.line); got != tt.want {
+				t.Errorf("IsRelevant() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsValidEmail(t *testing.T) {
+	tests := []struct {
+		address string
+		want    bool
+	}{
+		{"someone@example.com", true},
+		{"first.last+tag@sub.example.com", true},
+		{"o'brien@example.com", true},
+		{"a@b", true},
+		{"müller@bücher.de", true},
+		{"user@xn--bcher-kva.de", true},
+		{"用户@例子.广告", true},
+		{"", false},
+		{"@example.com", false},
+		{"someone@", false},
+		{"someone", false},
+		{"some one@example.com", false},
+		{"someone@-example.com", false},
+		{"someone@example-.com", false},
+		{"someone@example..com", false},
+		{"someone@exa_mple.com", false},
+		{"some\"one@example.com", false},
+		{"someone@" + string(make([]byte, 64)) + ".com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			if got := isValidEmail(tt.address); got != tt.want {
+				t.Errorf("isValidEmail(%q) = %v, want %v", tt.address, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitAddress(t *testing.T) {
+	tests := []struct {
+		address  string
+		wantUser string
+		wantHost string
+	}{
+		{"someone@example.com", "someone", "example.com"},
+		{"\"a@b\"@example.com", "\"a@b\"", "example.com"},
+		{NullSender, NullSender, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			user, host := splitAddress(tt.address)
+			if user != tt.wantUser || host != tt.wantHost {
+				t.Errorf("splitAddress(%q) = %q, %q, want %q, %q", tt.address, user, host, tt.wantUser, tt.wantHost)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package sglog
+
+import (
+	"sync"
+	"time"
+)
+
+// Aggregator combines SingleMail objects into MailData.
+// All methods are safe for concurrent use.
+type Aggregator struct {
+	mutex sync.Mutex
+	data  MailData
+	seen  map[string]bool
+}
+
+// NewAggregator creates an empty Aggregator with the creation time set to now.
+func NewAggregator() *Aggregator {
+	ag := &Aggregator{}
+	ag.data.CreateDateTime = time.Now()
+	ag.data.CreateDateTimeUnix = ag.data.CreateDateTime.Unix()
+	ag.data.CreateDate = ag.data.CreateDateTime.Format("2006-01-02")
+	ag.data.CreateTime = ag.data.CreateDateTime.Format("15:04:05")
+	return ag
+}
+
+// Add adds a single mail to the matching MailPartner.
+func (ag *Aggregator) Add(mail SingleMail) {
+	ag.mutex.Lock()
+	ag.data.Append(mail)
+	ag.mutex.Unlock()
+}
+
+// AddUnique adds a single mail to the matching MailPartner unless a mail with the same MailID was added by AddUnique before.
+// The returned bool is false if the mail was a duplicate. Mails without MailID are always added.
+func (ag *Aggregator) AddUnique(mail SingleMail) bool {
+	ag.mutex.Lock()
+	defer ag.mutex.Unlock()
+	if mail.MailID != "" {
+		if ag.seen == nil {
+			ag.seen = make(map[string]bool)
+		}
+		if ag.seen[mail.MailID] {
+			return false
+		}
+		ag.seen[mail.MailID] = true
+	}
+	ag.data.Append(mail)
+	return true
+}
+
+// AddData adds all mails stored in md by AddUnique, so partners and totals are computed again.
+// The number of added and skipped duplicate mails is returned.
+func (ag *Aggregator) AddData(md *MailData) (int64, int64) {
+	var added, duplicates int64
+	for _, partner := range md.Partner {
+		for _, mail := range partner.Mails {
+			if ag.AddUnique(mail) {
+				added++
+			} else {
+				duplicates++
+			}
+		}
+	}
+	return added, duplicates
+}
+
+// AddAll adds all mails received from mails until the channel is closed and returns the number of added mails.
+func (ag *Aggregator) AddAll(mails <-chan SingleMail) int64 {
+	var count int64
+	for mail := range mails {
+		ag.Add(mail)
+		count++
+	}
+	return count
+}
+
+// Data returns the aggregated MailData.
+// The returned MailData shares its partners with the Aggregator, so it should not be modified while mails are still added.
+func (ag *Aggregator) Data() MailData {
+	ag.mutex.Lock()
+	defer ag.mutex.Unlock()
+	return ag.data
+}
@@ -0,0 +1,103 @@
+package sglog
+
+import (
+	"fmt"
+	"testing"
+)
+
+// testMail returns a mail between two addresses, typed as internal for example.com and as external otherwise.
+func testMail(from string, to string, date string, clock string, subject string, size int64) SingleMail {
+	var mail SingleMail
+	mail.SetDate(date)
+	mail.SetTime(clock)
+	mail.SetFrom(from)
+	mail.SetTo(to)
+	typeOf := func(host string) string {
+		if host == "example.com" {
+			return "internal"
+		}
+		return "external"
+	}
+	mail.SetTypeFrom(typeOf(mail.HostFrom))
+	mail.SetTypeTo(typeOf(mail.HostTo))
+	mail.SetSubject(subject)
+	mail.Size = size
+	mail.SetQueueID(fmt.Sprintf("%s-%s-%s", date, clock, subject))
+	mail.GenerateMailID()
+	return mail
+}
+
+func TestAggregator(t *testing.T) {
+	first := testMail("a@example.com", "b@else.example.org", "2020-07-18", "10:00:00", "One", 10)
+	reply := testMail("b@else.example.org", "a@example.com", "2020-07-18", "11:00:00", "Re: One", 20)
+	reply.SetOffHours(true)
+	other := testMail("c@example.com", "a@example.com", "2020-07-18", "12:00:00", "Two", 40)
+
+	aggregator := NewAggregator()
+	aggregator.Add(first)
+	aggregator.Add(reply)
+	if !aggregator.AddUnique(other) {
+		t.Error("AddUnique() = false for a new mail")
+	}
+	if aggregator.AddUnique(other) {
+		t.Error("AddUnique() = true for a duplicate mail")
+	}
+	data := aggregator.Data()
+	if len(data.Partner) != 2 {
+		t.Fatalf("Data() has %d partners, want 2", len(data.Partner))
+	}
+
+	// Partners are ordered by host, so the external partner is PartnerA.
+	partner, ok := data.Partner["b@else.example.org a@example.com"]
+	if !ok {
+		t.Fatalf("Data() partners = %v, want key <b@else.example.org a@example.com>", data.Partner)
+	}
+	want := MailPartner{PartnerA: "b@else.example.org", PartnerB: "a@example.com", TypeA: "external", TypeB: "internal", Type: "e2i", MailsTotal: 2, SizeTotal: 30, MailsAtoB: 1, SizeAtoB: 20, MailsBtoA: 1, SizeBtoA: 10, IsTwoWay: true, MailsOffHours: 1, SizeOffHours: 20}
+	got := partner
+	got.UserA, got.HostA, got.UserB, got.HostB, got.Mails = "", "", "", "", nil
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("partner = %+v, want %+v", got, want)
+	}
+	if partner.FirstSeen() != "2020-07-18 10:00:00" || partner.LastSeen() != "2020-07-18 11:00:00" {
+		t.Errorf("FirstSeen(), LastSeen() = %q, %q", partner.FirstSeen(), partner.LastSeen())
+	}
+
+	internal := data.Partner["a@example.com c@example.com"]
+	if internal.Type != "i2i" || internal.MailsBtoA != 1 || internal.IsTwoWay {
+		t.Errorf("internal partner = %+v", internal)
+	}
+}
+
+func TestAggregatorAddData(t *testing.T) {
+	var md MailData
+	md.Append(testMail("a@example.com", "b@else.example.org", "2020-07-18", "10:00:00", "One", 10))
+	md.Append(testMail("a@example.com", "b@else.example.org", "2020-07-18", "11:00:00", "Two", 20))
+
+	aggregator := NewAggregator()
+	if added, duplicates := aggregator.AddData(&md); added != 2 || duplicates != 0 {
+		t.Errorf("AddData() = %d, %d, want 2, 0", added, duplicates)
+	}
+	if added, duplicates := aggregator.AddData(&md); added != 0 || duplicates != 2 {
+		t.Errorf("AddData() again = %d, %d, want 0, 2", added, duplicates)
+	}
+	data := aggregator.Data()
+	if partner := data.Partner["b@else.example.org a@example.com"]; partner.MailsTotal != 2 || partner.SizeTotal != 30 {
+		t.Errorf("partner mails, size = %d, %d, want 2, 30", partner.MailsTotal, partner.SizeTotal)
+	}
+}
+
+func TestMailPartnerBulk(t *testing.T) {
+	bulk := testMail("news@else.example.org", "a@example.com", "2020-07-18", "10:00:00", "Newsletter", 10)
+	bulk.SetBulk(true)
+	personal := testMail("a@example.com", "news@else.example.org", "2020-07-18", "11:00:00", "Unsubscribe", 10)
+
+	var md MailData
+	md.Append(bulk)
+	if !md.Partner["news@else.example.org a@example.com"].IsBulk {
+		t.Error("partner with bulk mails only is not bulk")
+	}
+	md.Append(personal)
+	if md.Partner["news@else.example.org a@example.com"].IsBulk {
+		t.Error("partner with a personal mail is bulk")
+	}
+}
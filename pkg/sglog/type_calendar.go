@@ -0,0 +1,94 @@
+package sglog
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Calendar classifies the date and time of mails as business hours or off-hours.
+// Working hours apply on all days except weekend days and holidays.
+type Calendar struct {
+	workStart int
+	workEnd   int
+	weekend   map[time.Weekday]bool
+	holidays  map[string]bool
+}
+
+// NewCalendar creates a Calendar from working hours like "08:00-18:00" and the names of weekend days like "sat" or "sunday"; empty names are ignored.
+// Working hours ending before they start span midnight, e.g. "22:00-06:00".
+func NewCalendar(workHours string, weekend []string) (*Calendar, error) {
+	c := &Calendar{weekend: make(map[time.Weekday]bool), holidays: make(map[string]bool)}
+	start, end, found := strings.Cut(workHours, "-")
+	if !found {
+		return nil, fmt.Errorf("Working hours <%s> must be given as start-end", workHours)
+	}
+	var startErr, endErr error
+	c.workStart, startErr = parseClock(strings.TrimSpace(start))
+	c.workEnd, endErr = parseClock(strings.TrimSpace(end))
+	if startErr != nil || endErr != nil || c.workStart == c.workEnd {
+		return nil, fmt.Errorf("Invalid working hours <%s>", workHours)
+	}
+	for _, day := range weekend {
+		if strings.TrimSpace(day) == "" {
+			continue
+		}
+		weekday, ok := parseWeekday(day)
+		if !ok {
+			return nil, fmt.Errorf("Unknown weekday <%s>", day)
+		}
+		c.weekend[weekday] = true
+	}
+	return c, nil
+}
+
+// AddHoliday adds a holiday given as ISO date like "2020-12-24"; the whole day counts as off-hours.
+func (c *Calendar) AddHoliday(date string) error {
+	if _, parseErr := time.Parse("2006-01-02", date); parseErr != nil {
+		return fmt.Errorf("Invalid holiday <%s>, expected YYYY-MM-DD", date)
+	}
+	c.holidays[date] = true
+	return nil
+}
+
+// IsOffHours returns true if a mail sent on date (YYYY-MM-DD) at clock (HH:MM:SS) was sent outside of working hours.
+// Unparsable dates and times are never considered off-hours.
+func (c *Calendar) IsOffHours(date string, clock string) bool {
+	day, dayErr := time.Parse("2006-01-02", date)
+	minute, clockErr := parseClock(clock)
+	if dayErr != nil || clockErr != nil {
+		return false
+	}
+	if c.holidays[date] || c.weekend[day.Weekday()] {
+		return true
+	}
+	if c.workStart < c.workEnd {
+		return minute < c.workStart || minute >= c.workEnd
+	}
+	return minute < c.workStart && minute >= c.workEnd
+}
+
+// parseClock returns the minutes since midnight of a time like "08:30" or "08:30:15"; seconds are ignored.
+func parseClock(clock string) (int, error) {
+	parts := strings.Split(clock, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid time <%s>", clock)
+	}
+	var hours, minutes int
+	if _, scanErr := fmt.Sscanf(parts[0]+" "+parts[1], "%d %d", &hours, &minutes); scanErr != nil || hours < 0 || hours > 24 || minutes < 0 || minutes > 59 || (hours == 24 && minutes > 0) {
+		return 0, fmt.Errorf("invalid time <%s>", clock)
+	}
+	return hours*60 + minutes, nil
+}
+
+// parseWeekday returns the weekday matching name, which may be abbreviated to three letters.
+func parseWeekday(name string) (time.Weekday, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		full := strings.ToLower(day.String())
+		if len(name) >= 3 && strings.HasPrefix(full, name) {
+			return day, true
+		}
+	}
+	return time.Sunday, false
+}
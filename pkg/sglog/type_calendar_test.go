@@ -0,0 +1,70 @@
+package sglog
+
+import "testing"
+
+func TestNewCalendar(t *testing.T) {
+	tests := []struct {
+		name      string
+		workHours string
+		weekend   []string
+		wantErr   bool
+	}{
+		{"default", "08:00-18:00", []string{"sat", "sun"}, false},
+		{"full day names", "08:00-18:00", []string{"Saturday", " sunday "}, false},
+		{"empty weekend", "08:00-18:00", []string{""}, false},
+		{"night shift", "22:00-06:00", nil, false},
+		{"until midnight", "08:00-24:00", nil, false},
+		{"missing end", "08:00", nil, true},
+		{"same start and end", "08:00-08:00", nil, true},
+		{"invalid hour", "08:00-25:00", nil, true},
+		{"invalid minute", "08:60-18:00", nil, true},
+		{"unknown weekday", "08:00-18:00", []string{"sa"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCalendar(tt.workHours, tt.weekend)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewCalendar(%q, %q) error = %v, wantErr %v", tt.workHours, tt.weekend, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCalendarIsOffHours(t *testing.T) {
+	office, _ := NewCalendar("08:00-18:00", []string{"sat", "sun"})
+	if holidayErr := office.AddHoliday("2020-12-24"); holidayErr != nil {
+		t.Fatal(holidayErr)
+	}
+	if holidayErr := office.AddHoliday("24.12.2020"); holidayErr == nil {
+		t.Error("AddHoliday() accepted a date that is not in ISO format")
+	}
+	night, _ := NewCalendar("22:00-06:00", nil)
+	tests := []struct {
+		name     string
+		calendar *Calendar
+		date     string
+		clock    string
+		want     bool
+	}{
+		{"start of working hours", office, "2020-07-15", "08:00:00", false},
+		{"during working hours", office, "2020-07-15", "12:30:15", false},
+		{"before working hours", office, "2020-07-15", "07:59:59", true},
+		{"end of working hours", office, "2020-07-15", "18:00:00", true},
+		{"saturday", office, "2020-07-18", "12:00:00", true},
+		{"sunday", office, "2020-07-19", "12:00:00", true},
+		{"holiday", office, "2020-12-24", "12:00:00", true},
+		{"night shift late", night, "2020-07-15", "23:00:00", false},
+		{"night shift early", night, "2020-07-15", "05:59:00", false},
+		{"night shift off", night, "2020-07-15", "12:00:00", true},
+		{"night shift without weekend", night, "2020-07-18", "23:00:00", false},
+		{"unparsable date", office, "18.07.2020", "03:00:00", false},
+		{"unparsable time", office, "2020-07-18", "3 am", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.calendar.IsOffHours(tt.date, tt.clock); got != tt.want {
+				t.Errorf("IsOffHours(%q, %q) = %v, want %v", tt.date, tt.clock, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package sglog
+
+// Config defines how a Parser parses and classifies log lines.
+type Config struct {
+	InternalHosts []string // Host parts to be considered as internal, in Unicode or Punycode form
+	SpecialUsers  []string // Local parts to be considered as special (e.g. postmaster)
+	LowerDomains  bool     // Convert domains to lower case
+	LowerLocal    bool     // Convert local parts to lower case
+	StripSubaddr  bool     // Remove subaddress tags like +tag from local parts
+	UnwrapAddrs   bool     // Restore original addresses rewritten by SRS or BATV
+	KeepRejected  bool     // Store skipped lines for retrieval with ParseStats.Rejected
+	Workers       int      // Number of goroutines parsing concurrently; defaults to the number of CPUs
+	SliceSize     int      // Number of log lines parsed at once by a goroutine; at least 10
+}
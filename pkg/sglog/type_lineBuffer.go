@@ -1,26 +1,26 @@
-package main
+package sglog
 
 import (
 	"fmt"
 	"sync"
 )
 
-// lineBuffer stores multiple logLines in a thread-safe way.
+// lineBuffer stores multiple LogLines in a thread-safe way.
 type lineBuffer struct {
 	mutex sync.Mutex
-	lines []logLine
+	lines []LogLine
 }
 
-// Push stores a new logLine at the end of the lineBuffer.
-func (lb *lineBuffer) Push(line logLine) error {
+// Push stores a new LogLine at the end of the lineBuffer.
+func (lb *lineBuffer) Push(line LogLine) error {
 	lb.mutex.Lock()
 	lb.lines = append(lb.lines, line)
 	lb.mutex.Unlock()
 	return nil
 }
 
-// PushSlice stores a number of new logLines at the end of the lineBuffer.
-func (lb *lineBuffer) PushSlice(lines []logLine) error {
+// PushSlice stores a number of new LogLines at the end of the lineBuffer.
+func (lb *lineBuffer) PushSlice(lines []LogLine) error {
 	lb.mutex.Lock()
 	for _, line := range lines {
 		lb.lines = append(lb.lines, line)
@@ -30,8 +30,9 @@ func (lb *lineBuffer) PushSlice(lines []logLine) error {
 }
 
 // PopSlice retrieves a number of elements off the end of the lineBuffer.
-func (lb *lineBuffer) PopSlice(elements int) ([]logLine, error) {
-	var logLines []logLine
+// The elements are copied, so the returned slice is not affected by later calls to Push or PushSlice.
+func (lb *lineBuffer) PopSlice(elements int) ([]LogLine, error) {
+	var logLines []LogLine
 
 	if elements < 1 {
 		return logLines, fmt.Errorf("need to fetch at least 1 element")
@@ -43,28 +44,27 @@ func (lb *lineBuffer) PopSlice(elements int) ([]logLine, error) {
 		lb.mutex.Unlock()
 		return logLines, fmt.Errorf("no elements in buffer")
 	}
-	if elements < lineCount {
-		start := lineCount - elements
-		logLines = lb.lines[start:]
-		lb.lines = lb.lines[:start]
-	} else {
-		logLines = lb.lines
-		lb.lines = lb.lines[:0]
+	if elements > lineCount {
+		elements = lineCount
 	}
+	start := lineCount - elements
+	logLines = make([]LogLine, elements)
+	copy(logLines, lb.lines[start:])
+	lb.lines = lb.lines[:start]
 	lb.mutex.Unlock()
 	return logLines, nil
 }
 
 // Pop retrieves an element off the end of the lineBuffer.
-func (lb *lineBuffer) Pop() (logLine, error) {
+func (lb *lineBuffer) Pop() (LogLine, error) {
 	lb.mutex.Lock()
 	n := len(lb.lines) - 1
 	if n < 0 {
 		lb.mutex.Unlock()
-		return logLine{}, fmt.Errorf("no elements in buffer")
+		return LogLine{}, fmt.Errorf("no elements in buffer")
 	}
 	line := lb.lines[n]
-	lb.lines[n] = logLine{}
+	lb.lines[n] = LogLine{}
 	lb.lines = lb.lines[:n]
 	lb.mutex.Unlock()
 	return line, nil
@@ -0,0 +1,23 @@
+package sglog
+
+// LogLine represents a single line of a logfile.
+type LogLine struct {
+	FileName   string
+	LineNumber uint32
+	Content    string
+}
+
+// File returns the name of the file where the LogLine was found.
+func (ll *LogLine) File() string {
+	return ll.FileName
+}
+
+// Line returns the line number where the LogLine was found.
+func (ll *LogLine) Line() uint32 {
+	return ll.LineNumber
+}
+
+// String returns the content of the LogLine.
+func (ll LogLine) String() string {
+	return ll.Content
+}
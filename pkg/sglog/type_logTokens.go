@@ -0,0 +1,92 @@
+package sglog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Stores a single key="value" pair of a log line.
+type logField struct {
+	Key   string
+	Value string
+}
+
+// logTokens stores the timestamp and all key="value" pairs of a single log line.
+// Keys and values reference the original line wherever possible, so tokenizing does not copy the line.
+type logTokens struct {
+	Date   string
+	Time   string
+	Fields []logField
+}
+
+// Get returns the value of the first field with the given key.
+// The returned bool is false if the key does not exist.
+func (lt *logTokens) Get(key string) (string, bool) {
+	for _, field := range lt.Fields {
+		if field.Key == key {
+			return field.Value, true
+		}
+	}
+	return "", false
+}
+
+// Reset clears a logTokens object while retaining the allocated memory for later use.
+func (lt *logTokens) Reset() {
+	lt.Date = ""
+	lt.Time = ""
+	lt.Fields = lt.Fields[:0]
+}
+
+// Tokenize walks line once and stores its timestamp and all key="value" pairs.
+// Lines are expected to start with a timestamp like "2020:07:18-16:56:31". Quotes within values may be escaped by a backslash.
+func (lt *logTokens) Tokenize(line string) error {
+	lt.Reset()
+
+	dateEnd := strings.IndexByte(line, '-')
+	timeEnd := strings.IndexByte(line, ' ')
+	if dateEnd < 1 || timeEnd <= dateEnd+1 {
+		return fmt.Errorf("line does not start with a timestamp")
+	}
+	lt.Date = strings.ReplaceAll(line[:dateEnd], ":", "-")
+	lt.Time = line[dateEnd+1 : timeEnd]
+
+	i := timeEnd
+	for i < len(line) {
+		// Skip to the start of the next word.
+		for i < len(line) && line[i] == ' ' {
+			i++
+		}
+		keyStart := i
+		for i < len(line) && line[i] != ' ' && line[i] != '=' {
+			i++
+		}
+		if i+1 >= len(line) || line[i] != '=' || line[i+1] != '"' {
+			// Not a key="value" pair, continue with the next word.
+			for i < len(line) && line[i] != ' ' {
+				i++
+			}
+			continue
+		}
+		key := line[keyStart:i]
+		i = i + 2
+		valueStart := i
+		escaped := false
+		for i < len(line) && line[i] != '"' {
+			if line[i] == '\\' {
+				escaped = true
+				i++
+			}
+			i++
+		}
+		if i >= len(line) {
+			return fmt.Errorf("value of <%s> is not terminated", key)
+		}
+		value := line[valueStart:i]
+		if escaped {
+			value = unescapeValue(value)
+		}
+		lt.Fields = append(lt.Fields, logField{Key: key, Value: value})
+		i++
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package sglog
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// Regular expressions used to parse log lines before the tokenizer was introduced, kept as reference implementation.
+var (
+	refDateTime   = regexp.MustCompile(`^(.+?)-(.+?)\s`)
+	refValidEmail = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
+	refFrom       = regexp.MustCompile(`\sfrom="(.*?)"\s?`)
+	refTo         = regexp.MustCompile(`\sto="(.*?)"\s?`)
+	refSubject    = regexp.MustCompile(`\ssubject="(.*?)"\s?`)
+	refSize       = regexp.MustCompile(`\ssize="(.+?)"\s?`)
+	refQueueID    = regexp.MustCompile(`\squeueid="(.+?)"\s?`)
+)
+
+// Stores the values extracted from a relevant log line.
+type testLineValues struct {
+	Date, Time, From, To, Subject, Size, QueueID string
+	ValidFrom, ValidTo                           bool
+}
+
+// regexpLineValues extracts the values of line using the reference regular expressions.
+func regexpLineValues(line string) testLineValues {
+	var v testLineValues
+	submatch := func(re *regexp.Regexp) string {
+		if match := re.FindStringSubmatch(line); len(match) == 2 {
+			return match[1]
+		}
+		return ""
+	}
+	if match := refDateTime.FindStringSubmatch(line); len(match) == 3 {
+		v.Date, v.Time = strings.ReplaceAll(match[1], ":", "-"), match[2]
+	}
+	v.From, v.To, v.Subject = submatch(refFrom), submatch(refTo), submatch(refSubject)
+	v.Size, v.QueueID = submatch(refSize), submatch(refQueueID)
+	v.ValidFrom, v.ValidTo = refValidEmail.MatchString(v.From), refValidEmail.MatchString(v.To)
+	return v
+}
+
+// tokenLineValues extracts the values of line using tokens.
+func tokenLineValues(tokens *logTokens, line string) (testLineValues, error) {
+	var v testLineValues
+	if tokenErr := tokens.Tokenize(line); tokenErr != nil {
+		return v, tokenErr
+	}
+	v.Date, v.Time = tokens.Date, tokens.Time
+	v.From, _ = tokens.Get("from")
+	v.To, _ = tokens.Get("to")
+	v.Subject, _ = tokens.Get("subject")
+	v.Size, _ = tokens.Get("size")
+	v.QueueID, _ = tokens.Get("queueid")
+	v.ValidFrom, v.ValidTo = isValidEmail(v.From), isValidEmail(v.To)
+	return v, nil
+}
+
+// testdataLines returns all relevant lines of the test data created by --create-testdata.
+func testdataLines(tb testing.TB) []string {
+	content, readErr := os.ReadFile("../../embedded-testdata.txt")
+	if readErr != nil {
+		tb.Fatalf("reading test data: %s", readErr)
+	}
+	var lines []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if IsRelevant(line) {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		tb.Fatal("test data contains no relevant lines")
+	}
+	return lines
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		date    string
+		time    string
+		fields  []logField
+		wantErr bool
+	}{
+		{"plain", `2020:07:18-16:56:31 some-sg smtpd[1]: from="a@b.c" size="12"`, "2020-07-18", "16:56:31", []logField{{"from", "a@b.c"}, {"size", "12"}}, false},
+		{"empty value", `2020:07:18-16:56:31 host from="" to="x@y.z"`, "2020-07-18", "16:56:31", []logField{{"from", ""}, {"to", "x@y.z"}}, false},
+		{"value with spaces", `2020:07:18-16:56:31 host subject="Re: hello there"`, "2020-07-18", "16:56:31", []logField{{"subject", "Re: hello there"}}, false},
+		{"escaped quote", `2020:07:18-16:56:31 host subject="say \"hi\"" size="1"`, "2020-07-18", "16:56:31", []logField{{"subject", `say "hi"`}, {"size", "1"}}, false},
+		{"words without value", `2020:07:18-16:56:31 host logfoo P=esmtp key="v"`, "2020-07-18", "16:56:31", []logField{{"key", "v"}}, false},
+		{"no timestamp", `smtpd[1]: from="a@b.c"`, "", "", nil, true},
+		{"unterminated value", `2020:07:18-16:56:31 host subject="open`, "", "", nil, true},
+	}
+	var tokens logTokens
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tokens.Tokenize(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Tokenize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if tokens.Date != tt.date || tokens.Time != tt.time {
+				t.Errorf("Tokenize() timestamp = %s %s, want %s %s", tokens.Date, tokens.Time, tt.date, tt.time)
+			}
+			if len(tokens.Fields) != len(tt.fields) {
+				t.Fatalf("Tokenize() fields = %v, want %v", tokens.Fields, tt.fields)
+			}
+			for i, field := range tt.fields {
+				if tokens.Fields[i] != field {
+					t.Errorf("Tokenize() field %d = %v, want %v", i, tokens.Fields[i], field)
+				}
+			}
+		})
+	}
+}
+
+func TestTokenizeMatchesRegexp(t *testing.T) {
+	var tokens logTokens
+	for _, line := range testdataLines(t) {
+		got, tokenErr := tokenLineValues(&tokens, line)
+		if tokenErr != nil {
+			t.Fatalf("Tokenize(%q) error = %s", line, tokenErr)
+		}
+		if want := regexpLineValues(line); got != want {
+			t.Errorf("values of %q = %+v, want %+v", line, got, want)
+		}
+	}
+}
+
+func BenchmarkTokenize(b *testing.B) {
+	lines := testdataLines(b)
+	var tokens logTokens
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		line := lines[i%len(lines)]
+		b.SetBytes(int64(len(line)))
+		if _, tokenErr := tokenLineValues(&tokens, line); tokenErr != nil {
+			b.Fatal(tokenErr)
+		}
+	}
+}
+
+func BenchmarkTokenizeRegexp(b *testing.B) {
+	lines := testdataLines(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		line := lines[i%len(lines)]
+		b.SetBytes(int64(len(line)))
+		regexpLineValues(line)
+	}
+}
@@ -1,24 +1,24 @@
-package main
+package sglog
 
 import (
 	"time"
 )
 
-// Stores an indexed array of mailPartner objects.
-type mailData struct {
+// MailData stores an indexed array of MailPartner objects.
+type MailData struct {
 	CreateDateTime     time.Time              `json:"createDateTime"`
 	CreateDateTimeUnix int64                  `json:"createDateTimeUnix"`
 	CreateDate         string                 `json:"createDate"`
 	CreateTime         string                 `json:"createTime"`
-	Partner            map[string]mailPartner `json:"partners"`
+	Partner            map[string]MailPartner `json:"partners"`
 }
 
-// Append adds a singleMail object to the matching mailPartner object.
-// If the mailPartner structure does not exist, Append will initialize it.
-func (md *mailData) Append(mail singleMail) {
+// Append adds a SingleMail object to the matching MailPartner object.
+// If the MailPartner structure does not exist, Append will initialize it.
+func (md *MailData) Append(mail SingleMail) {
 	partnerIndex := mail.GetPartnerKey()
 	if md.Partner == nil {
-		md.Partner = make(map[string]mailPartner)
+		md.Partner = make(map[string]MailPartner)
 	}
 	partner := md.Partner[partnerIndex]
 	if partner.MailsTotal < 1 {
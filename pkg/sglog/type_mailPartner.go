@@ -0,0 +1,182 @@
+package sglog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	MailPartnerCSVHeader string = "type,sizeAtoB,countAtoB,partnerA,partnerB,countBtoA,sizeBtoA,isTwoWay" // Header line matching MailPartner.ToCSV
+	mailPartnerCSVFormat string = "%s,%d,%d,%s,%s,%d,%d,%t"                                               // Format string for MailPartner.ToCSV
+)
+
+var (
+	// MailPartnerFields lists all fields that can be retrieved with MailPartner.Field, including the derived fields firstSeen and lastSeen.
+	MailPartnerFields = []string{"type", "partnerA", "userA", "hostA", "typeA", "partnerB", "userB", "hostB", "typeB", "mailsTotal", "sizeTotal", "mailsAtoB", "sizeAtoB", "mailsBtoA", "sizeBtoA", "isTwoWay", "isBulk", "mailsOffHours", "sizeOffHours", "firstSeen", "lastSeen"}
+)
+
+// MailPartner stores all mails belonging to a conversation alogn with statistics for that conversation.
+type MailPartner struct {
+	PartnerA      string       `json:"partnerA"`
+	UserA         string       `json:"userA"`
+	HostA         string       `json:"hostA"`
+	TypeA         string       `json:"typeA"`
+	PartnerB      string       `json:"partnerB"`
+	UserB         string       `json:"userB"`
+	HostB         string       `json:"hostB"`
+	TypeB         string       `json:"typeB"`
+	Type          string       `json:"type"`
+	MailsTotal    int64        `json:"mailsTotal"`
+	SizeTotal     int64        `json:"sizeTotal"`
+	MailsAtoB     int64        `json:"mailsAtoB"`
+	SizeAtoB      int64        `json:"sizeAtoB"`
+	MailsBtoA     int64        `json:"mailsBtoA"`
+	SizeBtoA      int64        `json:"sizeBtoA"`
+	IsTwoWay      bool         `json:"isTwoWay"`
+	IsBulk        bool         `json:"isBulk"`
+	MailsOffHours int64        `json:"mailsOffHours"`
+	SizeOffHours  int64        `json:"sizeOffHours"`
+	Mails         []SingleMail `json:"mails"`
+}
+
+// Init initializes the statistical fields of a MailPartner obejct.
+// The types of both partners are taken from the given SingleMail, so they are not classified again.
+func (mp *MailPartner) Init(mail SingleMail) {
+	partnerIndex := mail.GetPartnerKey()
+	commPartners := strings.Split(partnerIndex, " ")
+	mp.PartnerA = commPartners[0]
+	mp.PartnerB = commPartners[1]
+	mp.UserA, mp.HostA = mp.SplitAddress(mp.PartnerA)
+	mp.UserB, mp.HostB = mp.SplitAddress(mp.PartnerB)
+	mp.TypeA, mp.TypeB = mail.TypeFrom, mail.TypeTo
+	if mp.PartnerA != mail.From {
+		mp.TypeA, mp.TypeB = mail.TypeTo, mail.TypeFrom
+	}
+	mp.Type = fmt.Sprintf("%c2%c", mp.TypeA[0], mp.TypeB[0])
+	mp.MailsTotal = 0
+	mp.MailsAtoB = 0
+	mp.MailsBtoA = 0
+	mp.SizeTotal = 0
+	mp.SizeAtoB = 0
+	mp.SizeBtoA = 0
+	mp.IsTwoWay = false
+	mp.IsBulk = false
+	mp.MailsOffHours = 0
+	mp.SizeOffHours = 0
+}
+
+// SplitAddress splits up the given email address into user and host parts.
+func (mp *MailPartner) SplitAddress(email string) (string, string) {
+	return splitAddress(email)
+}
+
+// IsFromA returns true if the given SingleMail object is from PartnerA, else false.
+func (mp *MailPartner) IsFromA(mail SingleMail) bool {
+	return (mp.PartnerA == mail.From)
+}
+
+// IsFromB returns true if the given SingleMail object is from PartnerB, else false.
+func (mp *MailPartner) IsFromB(mail SingleMail) bool {
+	return !mp.IsFromA(mail)
+}
+
+// AddMail stores a SingleMail in the MailPartner structure and updates the statistics accordingly.
+func (mp *MailPartner) AddMail(mail SingleMail) {
+	mp.Mails = append(mp.Mails, mail)
+	mp.MailsTotal++
+	mp.SizeTotal = mp.SizeTotal + mail.Size
+	if mp.IsFromA(mail) {
+		mp.MailsAtoB++
+		mp.SizeAtoB = mp.SizeAtoB + mail.Size
+	} else {
+		mp.MailsBtoA++
+		mp.SizeBtoA = mp.SizeBtoA + mail.Size
+	}
+	if mail.IsOffHours {
+		mp.MailsOffHours++
+		mp.SizeOffHours = mp.SizeOffHours + mail.Size
+	}
+	if mp.MailsAtoB > 0 && mp.MailsBtoA > 0 {
+		mp.IsTwoWay = true
+	}
+	// A partner is bulk only as long as all of its mails are.
+	mp.IsBulk = mail.IsBulk && (mp.MailsTotal == 1 || mp.IsBulk)
+}
+
+// ToCSV returns a CSV representation of a MailPartner object.
+func (mp *MailPartner) ToCSV() string {
+	return fmt.Sprintf(mailPartnerCSVFormat, mp.Type, mp.SizeAtoB, mp.MailsAtoB, mp.PartnerA, mp.PartnerB, mp.MailsBtoA, mp.SizeBtoA, mp.IsTwoWay)
+}
+
+// FirstSeen returns the date and time of the earliest mail as "YYYY-MM-DD HH:MM:SS", or an empty string if there are no mails.
+func (mp *MailPartner) FirstSeen() string {
+	var first string
+	for _, mail := range mp.Mails {
+		if seen := mail.Date + " " + mail.Time; first == "" || seen < first {
+			first = seen
+		}
+	}
+	return first
+}
+
+// LastSeen returns the date and time of the latest mail as "YYYY-MM-DD HH:MM:SS", or an empty string if there are no mails.
+func (mp *MailPartner) LastSeen() string {
+	var last string
+	for _, mail := range mp.Mails {
+		if seen := mail.Date + " " + mail.Time; seen > last {
+			last = seen
+		}
+	}
+	return last
+}
+
+// Field returns the value of the field called name as listed in MailPartnerFields.
+// The names countAtoB and countBtoA used by MailPartnerCSVHeader are accepted as well. The returned bool is false for unknown fields.
+func (mp *MailPartner) Field(name string) (string, bool) {
+	switch name {
+	case "type":
+		return mp.Type, true
+	case "partnerA":
+		return mp.PartnerA, true
+	case "userA":
+		return mp.UserA, true
+	case "hostA":
+		return mp.HostA, true
+	case "typeA":
+		return mp.TypeA, true
+	case "partnerB":
+		return mp.PartnerB, true
+	case "userB":
+		return mp.UserB, true
+	case "hostB":
+		return mp.HostB, true
+	case "typeB":
+		return mp.TypeB, true
+	case "mailsTotal":
+		return strconv.FormatInt(mp.MailsTotal, 10), true
+	case "sizeTotal":
+		return strconv.FormatInt(mp.SizeTotal, 10), true
+	case "mailsAtoB", "countAtoB":
+		return strconv.FormatInt(mp.MailsAtoB, 10), true
+	case "sizeAtoB":
+		return strconv.FormatInt(mp.SizeAtoB, 10), true
+	case "mailsBtoA", "countBtoA":
+		return strconv.FormatInt(mp.MailsBtoA, 10), true
+	case "sizeBtoA":
+		return strconv.FormatInt(mp.SizeBtoA, 10), true
+	case "isTwoWay":
+		return strconv.FormatBool(mp.IsTwoWay), true
+	case "isBulk":
+		return strconv.FormatBool(mp.IsBulk), true
+	case "mailsOffHours":
+		return strconv.FormatInt(mp.MailsOffHours, 10), true
+	case "sizeOffHours":
+		return strconv.FormatInt(mp.SizeOffHours, 10), true
+	case "firstSeen":
+		return mp.FirstSeen(), true
+	case "lastSeen":
+		return mp.LastSeen(), true
+	}
+	return "", false
+}
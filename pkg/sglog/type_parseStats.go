@@ -0,0 +1,136 @@
+package sglog
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	SkipMalformed      string = "malformed line"          // Line does not consist of a timestamp and key="value" pairs
+	SkipFromMissing    string = "missing from"            // Line has no from field
+	SkipFromInvalid    string = "invalid from"            // Line has a from field that is not an e-mail address
+	SkipToEmpty        string = "empty to"                // Line has no or an empty to field
+	SkipToInvalid      string = "invalid to"              // Line has a to field that is not an e-mail address
+	SkipSubjectMissing string = "missing subject"         // Line has no subject field
+	SkipSizeInvalid    string = "missing or invalid size" // Line has no size field or it is not a number
+	SkipQueueIDMissing string = "missing queueid"         // Line has no or an empty queueid field
+)
+
+// RejectedLine stores a log line that could not be parsed along with the reason.
+type RejectedLine struct {
+	Line   LogLine
+	Reason string
+}
+
+// ParseStats counts processed files, lines and mails in a thread-safe way.
+type ParseStats struct {
+	files         atomic.Int64
+	linesRead     atomic.Int64
+	relevantLines atomic.Int64
+	parsedMails   atomic.Int64
+
+	mutex         sync.Mutex
+	skipped       map[string]int64
+	keepRejected  bool
+	rejectedLines []RejectedLine
+}
+
+// KeepRejected enables storing all skipped lines for later retrieval with Rejected.
+func (ps *ParseStats) KeepRejected() {
+	ps.mutex.Lock()
+	ps.keepRejected = true
+	ps.mutex.Unlock()
+}
+
+// AddFile increments the number of processed files.
+func (ps *ParseStats) AddFile() {
+	ps.files.Add(1)
+}
+
+// AddLines increments the number of read and relevant log lines.
+func (ps *ParseStats) AddLines(read int64, relevant int64) {
+	ps.linesRead.Add(read)
+	ps.relevantLines.Add(relevant)
+}
+
+// AddParsedMails increments the number of successfully parsed mails.
+func (ps *ParseStats) AddParsedMails(count int64) {
+	ps.parsedMails.Add(count)
+}
+
+// AddSkipped increments the number of lines skipped for the given reason.
+// If KeepRejected was called before, the line itself is stored as well.
+func (ps *ParseStats) AddSkipped(line LogLine, reason string) {
+	ps.mutex.Lock()
+	if ps.skipped == nil {
+		ps.skipped = make(map[string]int64)
+	}
+	ps.skipped[reason]++
+	if ps.keepRejected {
+		ps.rejectedLines = append(ps.rejectedLines, RejectedLine{Line: line, Reason: reason})
+	}
+	ps.mutex.Unlock()
+}
+
+// Files returns the number of processed files.
+func (ps *ParseStats) Files() int64 {
+	return ps.files.Load()
+}
+
+// LinesRead returns the number of log lines read from all files.
+func (ps *ParseStats) LinesRead() int64 {
+	return ps.linesRead.Load()
+}
+
+// RelevantLines returns the number of log lines that describe a passed mail.
+func (ps *ParseStats) RelevantLines() int64 {
+	return ps.relevantLines.Load()
+}
+
+// ParsedMails returns the number of successfully parsed mails.
+func (ps *ParseStats) ParsedMails() int64 {
+	return ps.parsedMails.Load()
+}
+
+// SkippedLines returns the number of relevant log lines that did not result in a parsed mail.
+func (ps *ParseStats) SkippedLines() int64 {
+	var total int64
+	ps.mutex.Lock()
+	for _, count := range ps.skipped {
+		total = total + count
+	}
+	ps.mutex.Unlock()
+	return total
+}
+
+// SkipReasons returns the number of skipped lines per reason, sorted by reason.
+func (ps *ParseStats) SkipReasons() ([]string, []int64) {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	var reasons []string
+	for reason := range ps.skipped {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	counts := make([]int64, len(reasons))
+	for i, reason := range reasons {
+		counts[i] = ps.skipped[reason]
+	}
+	return reasons, counts
+}
+
+// Rejected returns all skipped lines, sorted by file and line number.
+func (ps *ParseStats) Rejected() []RejectedLine {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	rejected := make([]RejectedLine, len(ps.rejectedLines))
+	copy(rejected, ps.rejectedLines)
+	sort.SliceStable(rejected, func(i, j int) bool {
+		if rejected[i].Line.FileName != rejected[j].Line.FileName {
+			return rejected[i].Line.FileName < rejected[j].Line.FileName
+		}
+		return rejected[i].Line.LineNumber < rejected[j].Line.LineNumber
+	})
+	return rejected
+}
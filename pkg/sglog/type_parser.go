@@ -0,0 +1,234 @@
+package sglog
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// Parser reads Sophos SG SMTP logfiles and parses all relevant lines into SingleMail objects.
+// All methods are safe for concurrent use.
+type Parser struct {
+	config Config
+	lines  lineBuffer
+	stats  ParseStats
+}
+
+// NewParser creates a Parser using the given config.
+// Internal hosts are converted into their ASCII form; an error is returned if one of them is not a valid host name.
+func NewParser(config Config) (*Parser, error) {
+	p := &Parser{config: config}
+	p.config.InternalHosts = make([]string, len(config.InternalHosts))
+	for i, host := range config.InternalHosts {
+		asciiHost, asciiErr := hostToASCII(host)
+		if asciiErr != nil || !isValidHost(asciiHost) {
+			return nil, fmt.Errorf("Internal host <%s> is not a valid host name", host)
+		}
+		p.config.InternalHosts[i] = asciiHost
+	}
+	p.config.SpecialUsers = append([]string(nil), config.SpecialUsers...)
+	if p.config.Workers < 1 {
+		p.config.Workers = runtime.NumCPU()
+	}
+	if p.config.SliceSize < 10 {
+		p.config.SliceSize = 10
+	}
+	if p.config.KeepRejected {
+		p.stats.KeepRejected()
+	}
+	return p, nil
+}
+
+// Stats returns the statistics of all files read and lines parsed by the Parser.
+func (p *Parser) Stats() *ParseStats {
+	return &p.stats
+}
+
+// ReadFile reads all relevant lines of a logfile for parsing with Mails. Files with the extension .gz are decompressed.
+// Relevant lines read before an error occurred are kept. Returned errors match ErrFileOpen, ErrFileRead or ErrGzipRead.
+func (p *Parser) ReadFile(fileName string) error {
+	file, fileErr := os.Open(fileName)
+	if fileErr != nil {
+		return &readError{kind: ErrFileOpen, err: fmt.Errorf("Failed to open file: %w", fileErr)}
+	}
+	defer file.Close()
+
+	if strings.HasSuffix(fileName, ".gz") {
+		gz, gzErr := gzip.NewReader(file)
+		if gzErr != nil {
+			return &readError{kind: ErrGzipRead, err: fmt.Errorf("Failed to open gzip'ed file <%s>: %w", fileName, gzErr)}
+		}
+		return p.read(gz, fileName, true)
+	}
+	return p.read(file, fileName, false)
+}
+
+// Read reads all relevant lines from r for parsing with Mails. The name is used to identify rejected lines.
+// Relevant lines read before an error occurred are kept. Returned errors match ErrFileRead.
+func (p *Parser) Read(r io.Reader, name string) error {
+	return p.read(r, name, false)
+}
+
+// read reads all relevant lines from r into the line buffer.
+// If isGzip is true, errors caused by corrupt data match ErrGzipRead.
+func (p *Parser) read(r io.Reader, name string, isGzip bool) error {
+	var lineNo uint32
+	var lines []LogLine
+
+	fileScanner := bufio.NewScanner(r)
+	fileScanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), MaxLineLength)
+	for fileScanner.Scan() {
+		lineNo++
+		line := fileScanner.Text()
+		if !IsRelevant(line) {
+			continue
+		}
+		lines = append(lines, LogLine{FileName: name, LineNumber: lineNo, Content: line})
+	}
+
+	p.stats.AddFile()
+	p.stats.AddLines(int64(lineNo), int64(len(lines)))
+	p.lines.PushSlice(lines)
+
+	if scanErr := fileScanner.Err(); scanErr != nil {
+		if isGzip && (errors.Is(scanErr, gzip.ErrChecksum) || errors.Is(scanErr, gzip.ErrHeader) || errors.Is(scanErr, io.ErrUnexpectedEOF)) {
+			return &readError{kind: ErrGzipRead, err: fmt.Errorf("Gzip'ed file <%s> is corrupt after line %d: %w", name, lineNo, scanErr)}
+		}
+		return &readError{kind: ErrFileRead, err: fmt.Errorf("Failed to read file <%s> after line %d: %w", name, lineNo, scanErr)}
+	}
+	return nil
+}
+
+// Mails parses all lines read so far concurrently and sends the parsed mails to the returned channel.
+// The channel is closed once all lines are parsed, so it must be drained by the caller. Lines that cannot be parsed are accounted for in Stats.
+func (p *Parser) Mails() <-chan SingleMail {
+	mails := make(chan SingleMail, p.config.SliceSize)
+	go func() {
+		threadMgmt := make(chan bool, p.config.Workers)
+		for p.lines.Len() > 0 {
+			lines, linesErr := p.lines.PopSlice(p.config.SliceSize)
+			if linesErr != nil {
+				break
+			}
+			threadMgmt <- true
+			go p.parseLineSlice(&threadMgmt, lines, mails)
+		}
+		waitAndClear(&threadMgmt)
+		close(mails)
+	}()
+	return mails
+}
+
+// parseLineSlice parses a slice of single log lines and sends the parsed mails to mails.
+// Lines that cannot be parsed are skipped and accounted for in the parse statistics.
+func (p *Parser) parseLineSlice(threadMgmt *chan bool, lines []LogLine, mails chan<- SingleMail) {
+	var tokens logTokens
+	var parsed int64
+
+	for _, singleLine := range lines {
+		mail, reason := p.parseLine(&tokens, singleLine)
+		if reason != "" {
+			p.stats.AddSkipped(singleLine, reason)
+			continue
+		}
+		mails <- mail
+		parsed++
+	}
+
+	p.stats.AddParsedMails(parsed)
+	<-*threadMgmt
+}
+
+// ParseLine parses a single log line into a SingleMail.
+// If the line cannot be parsed, the reason is returned as second value. Stats are not updated.
+func (p *Parser) ParseLine(line LogLine) (SingleMail, string) {
+	var tokens logTokens
+	return p.parseLine(&tokens, line)
+}
+
+// parseLine parses a single log line into a SingleMail, using tokens as temporary storage.
+// If the line cannot be parsed, the reason is returned as second value.
+func (p *Parser) parseLine(tokens *logTokens, line LogLine) (SingleMail, string) {
+	var mail SingleMail
+	if tokenErr := tokens.Tokenize(line.String()); tokenErr != nil {
+		return mail, SkipMalformed
+	}
+	mail.SetDate(tokens.Date)
+	mail.SetTime(tokens.Time)
+	from, fromFound := tokens.Get("from")
+	if !fromFound {
+		return mail, SkipFromMissing
+	} else if from != "" && from != "<>" && !isValidEmail(from) {
+		return mail, SkipFromInvalid
+	}
+	normalizedFrom := normalizeAddress(&p.config, from)
+	mail.SetFrom(normalizedFrom)
+	mail.SetTypeFrom(p.AddressType(mail.UserFrom, mail.HostFrom))
+	if normalizedFrom != from {
+		mail.SetFromRaw(from)
+	}
+	to, _ := tokens.Get("to")
+	if to == "" {
+		return mail, SkipToEmpty
+	} else if !isValidEmail(to) {
+		return mail, SkipToInvalid
+	}
+	normalizedTo := normalizeAddress(&p.config, to)
+	mail.SetTo(normalizedTo)
+	mail.SetTypeTo(p.AddressType(mail.UserTo, mail.HostTo))
+	if normalizedTo != to {
+		mail.SetToRaw(to)
+	}
+	subject, subjectFound := tokens.Get("subject")
+	if !subjectFound {
+		return mail, SkipSubjectMissing
+	}
+	mail.SetSubject(decodeSubject(subject))
+	size, _ := tokens.Get("size")
+	if sizeErr := mail.SetSize(size); sizeErr != nil {
+		return mail, SkipSizeInvalid
+	}
+	queueID, _ := tokens.Get("queueid")
+	if queueID == "" {
+		return mail, SkipQueueIDMissing
+	}
+	mail.SetQueueID(queueID)
+	srcIP, _ := tokens.Get("srcip")
+	mail.SetSrcIP(srcIP)
+	mail.GenerateMailID()
+	return mail, ""
+}
+
+// AddressType returns the type of a given address, split into user and host.
+// The null sender is of type "bounce". Users matching the configured special users are of type "special". Every other address is typed by HostType.
+func (p *Parser) AddressType(user string, host string) string {
+	if host == "" && user == NullSender {
+		return "bounce"
+	}
+	for _, specialUser := range p.config.SpecialUsers {
+		if strings.EqualFold(specialUser, user) {
+			return "special"
+		}
+	}
+	return p.HostType(host)
+}
+
+// HostType returns the type of a given host, either "internal" or "external".
+// Hosts matching the configured internal hosts are internal; every other host is considered as external. Hosts are compared in their ASCII form, so internationalized domains match regardless of being given in Unicode or Punycode.
+func (p *Parser) HostType(host string) string {
+	asciiHost, asciiErr := hostToASCII(host)
+	if asciiErr != nil {
+		return "external"
+	}
+	for _, intHost := range p.config.InternalHosts {
+		if strings.EqualFold(intHost, asciiHost) {
+			return "internal"
+		}
+	}
+	return "external"
+}
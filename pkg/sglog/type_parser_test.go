@@ -0,0 +1,208 @@
+package sglog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testLogLine returns a relevant log line sent at 2020-07-18 16:56:31 with the given key="value" pairs.
+func testLogLine(fields string) string {
+	return `2020:07:18-16:56:31 some-sg smtpd[14020]: SCANNER[14020]: id="1000" severity="info" sys="SecureMail" sub="smtp" name="email passed" ` + fields
+}
+
+func TestNewParser(t *testing.T) {
+	tests := []struct {
+		name          string
+		internalHosts []string
+		wantErr       bool
+	}{
+		{"ASCII host", []string{"example.com"}, false},
+		{"Unicode host", []string{"bücher.de"}, false},
+		{"Punycode host", []string{"xn--bcher-kva.de"}, false},
+		{"invalid host", []string{"exa_mple.com"}, true},
+		{"empty host", []string{""}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParser(Config{InternalHosts: tt.internalHosts})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewParser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	parser, parserErr := NewParser(Config{InternalHosts: []string{"example.com", "bücher.de"}, SpecialUsers: []string{"postmaster"}})
+	if parserErr != nil {
+		t.Fatal(parserErr)
+	}
+	tests := []struct {
+		name       string
+		line       string
+		wantReason string
+		wantType   string
+		wantFrom   string
+	}{
+		{"internal to external", testLogLine(`srcip="10.1.2.3" from="someone@example.com" to="other@else.example.org" subject="Hi" queueid="1abCdE-0a6b1f-A4" size="587538"`), "", "i2e", "someone@example.com"},
+		{"external to internal Punycode", testLogLine(`from="other@else.example.org" to="user@xn--bcher-kva.de" subject="Hi" queueid="q" size="1"`), "", "e2i", "other@else.example.org"},
+		{"bounce", testLogLine(`from="" to="someone@example.com" subject="Undeliverable" queueid="q" size="1"`), "", "b2i", NullSender},
+		{"special user", testLogLine(`from="Postmaster@example.com" to="someone@example.com" subject="Hi" queueid="q" size="1"`), "", "s2i", "Postmaster@example.com"},
+		{"escaped quote in subject", testLogLine(`from="a@example.com" to="b@example.com" subject="say \"hi\"" queueid="q" size="1"`), "", "i2i", "a@example.com"},
+		{"malformed", `2020:07:18-16:56:31 smtpd[1]: name="email passed" id="1000" subject="open`, SkipMalformed, "", ""},
+		{"missing from", testLogLine(`to="someone@example.com" subject="Hi" queueid="q" size="1"`), SkipFromMissing, "", ""},
+		{"invalid from", testLogLine(`from="not an address" to="someone@example.com" subject="Hi" queueid="q" size="1"`), SkipFromInvalid, "", ""},
+		{"empty to", testLogLine(`from="a@example.com" to="" subject="Hi" queueid="q" size="1"`), SkipToEmpty, "", ""},
+		{"invalid to", testLogLine(`from="a@example.com" to="nobody" subject="Hi" queueid="q" size="1"`), SkipToInvalid, "", ""},
+		{"missing subject", testLogLine(`from="a@example.com" to="b@example.com" queueid="q" size="1"`), SkipSubjectMissing, "", ""},
+		{"invalid size", testLogLine(`from="a@example.com" to="b@example.com" subject="Hi" queueid="q" size="big"`), SkipSizeInvalid, "", ""},
+		{"missing queueid", testLogLine(`from="a@example.com" to="b@example.com" subject="Hi" size="1"`), SkipQueueIDMissing, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mail, reason := parser.ParseLine(LogLine{Content: tt.line})
+			if reason != tt.wantReason {
+				t.Fatalf("ParseLine() reason = %q, want %q", reason, tt.wantReason)
+			}
+			if reason != "" {
+				return
+			}
+			if got := mail.GetType(); got != tt.wantType {
+				t.Errorf("ParseLine() type = %q, want %q", got, tt.wantType)
+			}
+			if mail.From != tt.wantFrom {
+				t.Errorf("ParseLine() from = %q, want %q", mail.From, tt.wantFrom)
+			}
+			if mail.Date != "2020-07-18" || mail.Time != "16:56:31" || mail.MailID == "" {
+				t.Errorf("ParseLine() date, time, mailID = %q, %q, %q", mail.Date, mail.Time, mail.MailID)
+			}
+		})
+	}
+}
+
+func TestParseLineNormalization(t *testing.T) {
+	parser, _ := NewParser(Config{InternalHosts: []string{"example.com"}, LowerDomains: true, StripSubaddr: true})
+	mail, reason := parser.ParseLine(LogLine{Content: testLogLine(`from="John+tag@Example.COM" to="b@example.com" subject="Hi" queueid="q" size="1"`)})
+	if reason != "" {
+		t.Fatalf("ParseLine() reason = %q", reason)
+	}
+	if mail.From != "John@example.com" || mail.FromRaw != "John+tag@Example.COM" || mail.TypeFrom != "internal" {
+		t.Errorf("ParseLine() from, fromRaw, typeFrom = %q, %q, %q", mail.From, mail.FromRaw, mail.TypeFrom)
+	}
+	if mail.ToRaw != "" {
+		t.Errorf("ParseLine() toRaw = %q, want empty for unchanged address", mail.ToRaw)
+	}
+}
+
+func TestParserRead(t *testing.T) {
+	content := strings.Join([]string{
+		testLogLine(`from="a@example.com" to="b@else.example.org" subject="One" queueid="q1" size="10"`),
+		`2020:07:18-16:56:31 some-sg exim-in[24020]: logfoo P=esmtp`,
+		testLogLine(`from="b@else.example.org" to="a@example.com" subject="Re: One" queueid="q2" size="20"`),
+		testLogLine(`from="a@example.com" to="nobody" subject="Two" queueid="q3" size="30"`),
+		testLogLine(`from="c@example.com" to="a@example.com" subject="Three" queueid="q4" size="40"`),
+	}, "\n")
+	parser, _ := NewParser(Config{InternalHosts: []string{"example.com"}, KeepRejected: true, Workers: 2})
+	if readErr := parser.Read(strings.NewReader(content), "test.log"); readErr != nil {
+		t.Fatal(readErr)
+	}
+	aggregator := NewAggregator()
+	if added := aggregator.AddAll(parser.Mails()); added != 3 {
+		t.Errorf("AddAll() = %d, want 3", added)
+	}
+	stats := parser.Stats()
+	if stats.Files() != 1 || stats.LinesRead() != 5 || stats.RelevantLines() != 4 || stats.ParsedMails() != 3 || stats.SkippedLines() != 1 {
+		t.Errorf("Stats() files, read, relevant, parsed, skipped = %d, %d, %d, %d, %d, want 1, 5, 4, 3, 1", stats.Files(), stats.LinesRead(), stats.RelevantLines(), stats.ParsedMails(), stats.SkippedLines())
+	}
+	reasons, counts := stats.SkipReasons()
+	if len(reasons) != 1 || reasons[0] != SkipToInvalid || counts[0] != 1 {
+		t.Errorf("SkipReasons() = %v, %v", reasons, counts)
+	}
+	rejected := stats.Rejected()
+	if len(rejected) != 1 || rejected[0].Line.FileName != "test.log" || rejected[0].Line.LineNumber != 4 || rejected[0].Reason != SkipToInvalid {
+		t.Errorf("Rejected() = %+v", rejected)
+	}
+	data := aggregator.Data()
+	if len(data.Partner) != 2 {
+		t.Errorf("Data() has %d partners, want 2", len(data.Partner))
+	}
+}
+
+func TestParserReadFile(t *testing.T) {
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "plain.log")
+	if writeErr := os.WriteFile(plain, []byte(testLogLine(`from="a@example.com" to="b@example.com" subject="Hi" queueid="q" size="1"`)+"\n"), 0o600); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+	corrupt := filepath.Join(dir, "corrupt.log.gz")
+	if writeErr := os.WriteFile(corrupt, []byte("not gzip'ed"), 0o600); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+	parser, _ := NewParser(Config{})
+	if readErr := parser.ReadFile(plain); readErr != nil {
+		t.Errorf("ReadFile(plain) error = %v", readErr)
+	}
+	if readErr := parser.ReadFile(filepath.Join(dir, "missing.log")); !errors.Is(readErr, ErrFileOpen) {
+		t.Errorf("ReadFile(missing) error = %v, want ErrFileOpen", readErr)
+	}
+	if readErr := parser.ReadFile(corrupt); !errors.Is(readErr, ErrGzipRead) {
+		t.Errorf("ReadFile(corrupt) error = %v, want ErrGzipRead", readErr)
+	}
+}
+
+func TestParserReadFileWhileParsing(t *testing.T) {
+	const files, linesPerFile = 20, 50
+	dir := t.TempDir()
+	var fileNames []string
+	for i := 0; i < files; i++ {
+		var content strings.Builder
+		for j := 0; j < linesPerFile; j++ {
+			content.WriteString(testLogLine(fmt.Sprintf(`from="a@example.com" to="b@example.com" subject="Hi" queueid="q%d-%d" size="1"`, i, j)) + "\n")
+		}
+		fileName := filepath.Join(dir, fmt.Sprintf("mail-%d.log", i))
+		if writeErr := os.WriteFile(fileName, []byte(content.String()), 0o600); writeErr != nil {
+			t.Fatal(writeErr)
+		}
+		fileNames = append(fileNames, fileName)
+	}
+
+	parser, _ := NewParser(Config{InternalHosts: []string{"example.com"}, SliceSize: 10, Workers: 4})
+	done := make(chan bool)
+	go func() {
+		for _, fileName := range fileNames {
+			if readErr := parser.ReadFile(fileName); readErr != nil {
+				t.Error(readErr)
+			}
+		}
+		close(done)
+	}()
+
+	seen := make(map[string]int)
+	collect := func() {
+		for mail := range parser.Mails() {
+			seen[mail.QueueID]++
+		}
+	}
+	for reading := true; reading; {
+		select {
+		case <-done:
+			reading = false
+		default:
+			collect()
+		}
+	}
+	collect()
+
+	if len(seen) != files*linesPerFile {
+		t.Errorf("Mails() returned %d distinct mails, want %d", len(seen), files*linesPerFile)
+	}
+	for queueID, count := range seen {
+		if count != 1 {
+			t.Errorf("Mails() returned mail <%s> %d times, want once", queueID, count)
+		}
+	}
+}
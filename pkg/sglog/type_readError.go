@@ -0,0 +1,18 @@
+package sglog
+
+// readError describes a logfile that could not be read completely.
+// It matches its kind (ErrFileOpen, ErrFileRead or ErrGzipRead) as well as the underlying error with errors.Is.
+type readError struct {
+	kind error
+	err  error
+}
+
+// Error returns the message of the underlying error.
+func (re *readError) Error() string {
+	return re.err.Error()
+}
+
+// Unwrap returns the kind and the underlying error.
+func (re *readError) Unwrap() []error {
+	return []error{re.kind, re.err}
+}
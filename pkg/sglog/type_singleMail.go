@@ -0,0 +1,158 @@
+package sglog
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+)
+
+// SingleMail stores parsed information for a single e-mail.
+type SingleMail struct {
+	MailID     string `json:"mailID"`
+	QueueID    string `json:"queueID"`
+	SrcIP      string `json:"srcIP"`
+	Date       string `json:"date"`
+	Time       string `json:"time"`
+	From       string `json:"from"`
+	FromRaw    string `json:"fromRaw,omitempty"`
+	HostFrom   string `json:"hostFrom"`
+	UserFrom   string `json:"userFrom"`
+	TypeFrom   string `json:"typeFrom"`
+	To         string `json:"to"`
+	ToRaw      string `json:"toRaw,omitempty"`
+	HostTo     string `json:"hostTo"`
+	UserTo     string `json:"userTo"`
+	TypeTo     string `json:"typeTo"`
+	Size       int64  `json:"size"`
+	Subject    string `json:"subject"`
+	IsBulk     bool   `json:"isBulk"`
+	IsOffHours bool   `json:"isOffHours"`
+}
+
+// SetDate sets the Date value of a SingleMail object.
+// No additional parsing is done.
+func (sm *SingleMail) SetDate(date string) {
+	sm.Date = date
+}
+
+// SetTime sets the Time value of a SingleMail object.
+// No additional parsing is done.
+func (sm *SingleMail) SetTime(time string) {
+	sm.Time = time
+}
+
+// SetFrom sets the From value of a SingleMail object.
+// It also splits up the given address and populates the HostFrom and UserFrom values.
+// An empty sender as used for bounces is stored as NullSender.
+func (sm *SingleMail) SetFrom(from string) {
+	if from == "" || from == "<>" {
+		from = NullSender
+	}
+	sm.From = from
+	sm.UserFrom, sm.HostFrom = splitAddress(from)
+}
+
+// SetTo sets the To value of a SingleMail object.
+// It also splits up the given address and populates the HostTo and UserTo values.
+func (sm *SingleMail) SetTo(to string) {
+	sm.To = to
+	sm.UserTo, sm.HostTo = splitAddress(to)
+}
+
+// SetTypeFrom sets the TypeFrom value of a SingleMail object.
+// No additional parsing is done.
+func (sm *SingleMail) SetTypeFrom(typeFrom string) {
+	sm.TypeFrom = typeFrom
+}
+
+// SetTypeTo sets the TypeTo value of a SingleMail object.
+// No additional parsing is done.
+func (sm *SingleMail) SetTypeTo(typeTo string) {
+	sm.TypeTo = typeTo
+}
+
+// SetFromRaw sets the FromRaw value of a SingleMail object.
+// It is meant to store the original sender if From was normalized.
+func (sm *SingleMail) SetFromRaw(from string) {
+	sm.FromRaw = from
+}
+
+// SetToRaw sets the ToRaw value of a SingleMail object.
+// It is meant to store the original recipient if To was normalized.
+func (sm *SingleMail) SetToRaw(to string) {
+	sm.ToRaw = to
+}
+
+// SetBulk sets the IsBulk value of a SingleMail object.
+// It is meant to mark mails of senders classified by BulkSenders.
+func (sm *SingleMail) SetBulk(isBulk bool) {
+	sm.IsBulk = isBulk
+}
+
+// SetOffHours sets the IsOffHours value of a SingleMail object.
+// It is meant to mark mails sent outside of working hours as classified by a Calendar.
+func (sm *SingleMail) SetOffHours(isOffHours bool) {
+	sm.IsOffHours = isOffHours
+}
+
+// SetSubject sets the Subject value of a SingleMail object.
+// No additional parsing is done.
+func (sm *SingleMail) SetSubject(subject string) {
+	sm.Subject = subject
+}
+
+// SetSize sets the Size value of a SingleMail object.
+// No additional parsing - apart from converting the given string into an int - is done. If size is not a number, Size is set to -1 and an error is returned.
+func (sm *SingleMail) SetSize(size string) error {
+	mailSize, mailSizeErr := strconv.ParseInt(size, 10, 64)
+	if mailSizeErr != nil {
+		sm.Size = -1
+		return mailSizeErr
+	}
+	sm.Size = mailSize
+	return nil
+}
+
+// SetQueueID sets the QueueID value of a SingleMail object.
+// No additional parsing is done.
+func (sm *SingleMail) SetQueueID(queueID string) {
+	sm.QueueID = queueID
+}
+
+// SetSrcIP sets the SrcIP value of a SingleMail object.
+// No additional parsing is done.
+func (sm *SingleMail) SetSrcIP(srcIP string) {
+	sm.SrcIP = srcIP
+}
+
+// GenerateMailID computes and sets the MailID value of a SingleMail object.
+// The MailID is generated by sha256'ing a string consisting of the QueueID, Date, Time, From and To values. The values are seperated by spaces.
+func (sm *SingleMail) GenerateMailID() {
+	idString := fmt.Sprintf("%s %s %s %s %s", sm.QueueID, sm.Date, sm.Time, sm.From, sm.To)
+	mailID := sha256.Sum256([]byte(idString))
+	sm.MailID = fmt.Sprintf("%x", mailID)
+}
+
+// GetPartnerKey returns the partner key of a SingleMail object.
+// The partner key is generated by sorting the host parts. If the host parts are equal, it is generated by sorting the full addresses.
+func (sm *SingleMail) GetPartnerKey() string {
+	commPartnerA := sm.From
+	commPartnerB := sm.To
+
+	if sm.HostFrom == sm.HostTo {
+		if sm.From > sm.To {
+			commPartnerA = sm.To
+			commPartnerB = sm.From
+		}
+	} else if sm.HostFrom > sm.HostTo {
+		commPartnerA = sm.To
+		commPartnerB = sm.From
+	}
+
+	return fmt.Sprintf("%s %s", commPartnerA, commPartnerB)
+}
+
+// GetType returns the type of a SingleMail object, for example "i2e" for mails sent from an internal to an external host.
+func (sm *SingleMail) GetType() string {
+	return fmt.Sprintf("%c2%c", sm.TypeFrom[0], sm.TypeTo[0])
+}
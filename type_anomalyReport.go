@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"gitlab.com/rbrt-weiler/sophos-sg-smtp-logparser/pkg/sglog"
+)
+
+var (
+	// Format strings for CSV output
+	anomalyReportCSVHeader = "kind,key,type,bucket,metric,value,baseline,score"
+	anomalyReportCSVFormat = "%s,%s,%s,%s,%s,%.0f,%.0f,%.2f"
+
+	// Layouts of the supported time buckets
+	anomalyBucketLayouts = map[string]string{"day": "2006-01-02", "hour": "2006-01-02 15"}
+)
+
+// Stores the mail volume of a single sender, partner or route per time bucket.
+type anomalyHistory struct {
+	Type        string
+	Mails       map[string]float64
+	Bytes       map[string]float64
+	SizeCount   float64
+	SizeSum     float64
+	SizeSquares float64
+}
+
+// add accounts for a single mail of the given size in bucket.
+func (ah *anomalyHistory) add(bucket string, size int64) {
+	ah.Mails[bucket]++
+	ah.Bytes[bucket] = ah.Bytes[bucket] + float64(size)
+	ah.SizeCount++
+	ah.SizeSum = ah.SizeSum + float64(size)
+	ah.SizeSquares = ah.SizeSquares + float64(size)*float64(size)
+}
+
+// sizeStats returns mean and standard deviation of the sizes of all mails.
+func (ah *anomalyHistory) sizeStats() (float64, float64) {
+	if ah.SizeCount == 0 {
+		return 0, 0
+	}
+	mean := ah.SizeSum / ah.SizeCount
+	variance := ah.SizeSquares/ah.SizeCount - mean*mean
+	return mean, math.Sqrt(math.Max(variance, 0))
+}
+
+// Stores a single sender, partner or route whose volume deviates from its baseline.
+type anomalyEntry struct {
+	Kind     string  `json:"kind"`
+	Key      string  `json:"key"`
+	Type     string  `json:"type"`
+	Bucket   string  `json:"bucket"`
+	Metric   string  `json:"metric"`
+	Value    float64 `json:"value"`
+	Baseline float64 `json:"baseline"`
+	Score    float64 `json:"score"`
+}
+
+// Stores all senders, partners and routes whose volume deviates from the baseline.
+type anomalyReport struct {
+	Bucket          string         `json:"bucket"`
+	Threshold       float64        `json:"threshold"`
+	BaselineFrom    string         `json:"baselineFrom"`
+	BaselineTo      string         `json:"baselineTo"`
+	BaselineBuckets int            `json:"baselineBuckets"`
+	Anomalies       []anomalyEntry `json:"anomalies"`
+}
+
+// collectAnomalyHistory groups all mails in md by sender, by partner and by route (sender to recipient domain) into time buckets.
+// The first and last bucket containing mails are returned as well.
+func collectAnomalyHistory(md *sglog.MailData, layout string) (map[string]*anomalyHistory, map[string]*anomalyHistory, map[string]*anomalyHistory, string, string) {
+	senders := make(map[string]*anomalyHistory)
+	partners := make(map[string]*anomalyHistory)
+	routes := make(map[string]*anomalyHistory)
+	var first, last string
+	get := func(histories map[string]*anomalyHistory, key string, mailType string) *anomalyHistory {
+		if _, ok := histories[key]; !ok {
+			histories[key] = &anomalyHistory{Type: mailType, Mails: make(map[string]float64), Bytes: make(map[string]float64)}
+		}
+		return histories[key]
+	}
+	for _, partner := range md.Partner {
+		partnerKey := partner.PartnerA + " <-> " + partner.PartnerB
+		for _, mail := range partner.Mails {
+			bucket := fmt.Sprintf("%s %s", mail.Date, mail.Time)
+			if len(bucket) < len(layout) {
+				continue
+			}
+			bucket = bucket[:len(layout)]
+			get(senders, mail.From, mail.TypeFrom).add(bucket, mail.Size)
+			get(partners, partnerKey, partner.Type).add(bucket, mail.Size)
+			get(routes, mail.From+" -> "+displayHost(mail.HostTo, mail.To), mail.GetType()).add(bucket, mail.Size)
+			if first == "" || bucket < first {
+				first = bucket
+			}
+			if bucket > last {
+				last = bucket
+			}
+		}
+	}
+	return senders, partners, routes, first, last
+}
+
+// countAnomalyBuckets returns the number of time buckets from first to last, including both.
+func countAnomalyBuckets(first string, last string, bucket string) int {
+	layout := anomalyBucketLayouts[bucket]
+	start, startErr := time.Parse(layout, first)
+	end, endErr := time.Parse(layout, last)
+	if startErr != nil || endErr != nil || end.Before(start) {
+		return 1
+	}
+	step := 24 * time.Hour
+	if bucket == "hour" {
+		step = time.Hour
+	}
+	return int(end.Sub(start)/step) + 1
+}
+
+// seriesStats returns mean and standard deviation of values over count buckets; buckets without value count as zero.
+func seriesStats(values map[string]float64, count int) (float64, float64) {
+	if count < 1 {
+		return 0, 0
+	}
+	var sum, squares float64
+	for _, value := range values {
+		sum = sum + value
+		squares = squares + value*value
+	}
+	mean := sum / float64(count)
+	variance := squares/float64(count) - mean*mean
+	return mean, math.Sqrt(math.Max(variance, 0))
+}
+
+// newAnomalyReport compares the volume of all senders, partners and routes in md per time bucket with their volume in baseline.
+// Only increases are scored; entries with a score of at least threshold are reported.
+func newAnomalyReport(md *sglog.MailData, baseline *sglog.MailData, bucket string, threshold float64) anomalyReport {
+	layout := anomalyBucketLayouts[bucket]
+	ar := anomalyReport{Bucket: bucket, Threshold: threshold, Anomalies: []anomalyEntry{}}
+
+	baseSenders, basePartners, baseRoutes, baseFirst, baseLast := collectAnomalyHistory(baseline, layout)
+	curSenders, curPartners, curRoutes, _, _ := collectAnomalyHistory(md, layout)
+	ar.BaselineFrom, ar.BaselineTo = baseFirst, baseLast
+	ar.BaselineBuckets = countAnomalyBuckets(baseFirst, baseLast, bucket)
+
+	// Byte volumes are measured in units of the average mail size, so they can be scored like mail counts.
+	var global anomalyHistory
+	for _, history := range baseSenders {
+		global.SizeCount = global.SizeCount + history.SizeCount
+		global.SizeSum = global.SizeSum + history.SizeSum
+		global.SizeSquares = global.SizeSquares + history.SizeSquares
+	}
+	unit, _ := global.sizeStats()
+	if unit < 1 {
+		unit = 1
+	}
+
+	score := func(value float64, mean float64, stdDev float64) float64 {
+		// The standard deviation is at least that of a Poisson process with the same mean, so rare partners do not produce huge scores.
+		return (value - mean) / math.Max(stdDev, math.Sqrt(math.Max(mean, 1)))
+	}
+	detect := func(kind string, current map[string]*anomalyHistory, base map[string]*anomalyHistory) {
+		for key, history := range current {
+			baseHistory, ok := base[key]
+			if !ok {
+				baseHistory = &anomalyHistory{}
+			}
+			mailsMean, mailsStdDev := seriesStats(baseHistory.Mails, ar.BaselineBuckets)
+			bytesMean, bytesStdDev := seriesStats(baseHistory.Bytes, ar.BaselineBuckets)
+			sizeMean, sizeStdDev := baseHistory.sizeStats()
+			if baseHistory.SizeCount == 0 {
+				sizeMean, sizeStdDev = global.sizeStats()
+			}
+			best := anomalyEntry{Kind: kind, Key: key, Type: history.Type}
+			consider := func(bucket string, metric string, value float64, baseValue float64, entryScore float64) {
+				if entryScore > best.Score {
+					best.Bucket, best.Metric, best.Value, best.Baseline, best.Score = bucket, metric, value, baseValue, entryScore
+				}
+			}
+			buckets := make([]string, 0, len(history.Mails))
+			for bucket := range history.Mails {
+				buckets = append(buckets, bucket)
+			}
+			sort.Strings(buckets)
+			for _, bucket := range buckets {
+				mails := history.Mails[bucket]
+				bytes := history.Bytes[bucket]
+				consider(bucket, "mails", mails, mailsMean, score(mails, mailsMean, mailsStdDev))
+				consider(bucket, "bytes", bytes, bytesMean, score(bytes/unit, bytesMean/unit, bytesStdDev/unit))
+				average := bytes / mails
+				consider(bucket, "avgSize", average, sizeMean, (average-sizeMean)/math.Max(sizeStdDev, sizeMean/10+1))
+			}
+			if best.Score >= threshold {
+				best.Score = math.Round(best.Score*100) / 100
+				ar.Anomalies = append(ar.Anomalies, best)
+			}
+		}
+	}
+	detect("sender", curSenders, baseSenders)
+	detect("partner", curPartners, basePartners)
+	detect("route", curRoutes, baseRoutes)
+
+	sort.Slice(ar.Anomalies, func(i, j int) bool {
+		a, b := ar.Anomalies[i], ar.Anomalies[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Key < b.Key
+	})
+	return ar
+}
+
+// formatAnomalyValue returns a human-friendly representation of value as measured by metric.
+func formatAnomalyValue(metric string, value float64) string {
+	if metric == "mails" {
+		return fmt.Sprintf("%.1f", value)
+	}
+	return formatBytes(int64(math.Round(value)))
+}
+
+// ToCSV returns a CSV representation of an anomalyReport object.
+func (ar *anomalyReport) ToCSV(withHeader bool) string {
+	var lines []string
+	if withHeader {
+		lines = append(lines, anomalyReportCSVHeader)
+	}
+	for _, entry := range ar.Anomalies {
+		lines = append(lines, fmt.Sprintf(anomalyReportCSVFormat, entry.Kind, entry.Key, entry.Type, entry.Bucket, entry.Metric, entry.Value, entry.Baseline, entry.Score))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ToTable returns a human-readable representation of an anomalyReport object.
+func (ar *anomalyReport) ToTable() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Anomalies with a score of at least %.1f, compared to %d %s buckets from %s to %s\n\n", ar.Threshold, ar.BaselineBuckets, ar.Bucket, ar.BaselineFrom, ar.BaselineTo)
+	var tt textTable
+	tt.SetHeader("score", "kind", "type", "key", ar.Bucket, "metric", "value", "baseline")
+	tt.AlignRight(0, 6, 7)
+	for _, entry := range ar.Anomalies {
+		tt.AddRow(fmt.Sprintf("%.2f", entry.Score), entry.Kind, entry.Type, entry.Key, entry.Bucket, entry.Metric, formatAnomalyValue(entry.Metric, entry.Value), formatAnomalyValue(entry.Metric, entry.Baseline))
+	}
+	sb.WriteString(tt.String())
+	return sb.String()
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+
+	"gitlab.com/rbrt-weiler/sophos-sg-smtp-logparser/pkg/sglog"
+)
+
+func TestCountAnomalyBuckets(t *testing.T) {
+	tests := []struct {
+		name   string
+		first  string
+		last   string
+		bucket string
+		want   int
+	}{
+		{"single day", "2020-07-18", "2020-07-18", "day", 1},
+		{"ten days", "2020-07-11", "2020-07-20", "day", 10},
+		{"across months", "2020-06-30", "2020-07-01", "day", 2},
+		{"hours", "2020-07-18 08", "2020-07-18 17", "hour", 10},
+		{"hours across days", "2020-07-18 23", "2020-07-19 01", "hour", 3},
+		{"reversed", "2020-07-20", "2020-07-11", "day", 1},
+		{"empty baseline", "", "", "day", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := countAnomalyBuckets(tt.first, tt.last, tt.bucket); got != tt.want {
+				t.Errorf("countAnomalyBuckets(%q, %q, %q) = %d, want %d", tt.first, tt.last, tt.bucket, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeriesStats(t *testing.T) {
+	tests := []struct {
+		name       string
+		values     map[string]float64
+		count      int
+		wantMean   float64
+		wantStdDev float64
+	}{
+		{"no buckets", nil, 0, 0, 0},
+		{"constant", map[string]float64{"a": 2, "b": 2}, 2, 2, 0},
+		{"missing buckets count as zero", map[string]float64{"a": 4}, 4, 1, math.Sqrt(3)},
+		{"varying", map[string]float64{"a": 1, "b": 3}, 2, 2, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mean, stdDev := seriesStats(tt.values, tt.count)
+			if math.Abs(mean-tt.wantMean) > 1e-9 || math.Abs(stdDev-tt.wantStdDev) > 1e-9 {
+				t.Errorf("seriesStats() = %v, %v, want %v, %v", mean, stdDev, tt.wantMean, tt.wantStdDev)
+			}
+		})
+	}
+}
+
+func TestNewAnomalyReport(t *testing.T) {
+	// Two senders with two mails of equal size on every day of the baseline
+	var baseline sglog.MailData
+	for day := 11; day <= 20; day++ {
+		for i, clock := range []string{"10:00:00", "14:00:00"} {
+			baseline.Append(testMail("a@example.com", "b@else.example.org", fmt.Sprintf("2020-07-%02d", day), clock, fmt.Sprintf("Report %d", i), 1000))
+			baseline.Append(testMail("c@example.com", "d@else.example.org", fmt.Sprintf("2020-07-%02d", day), clock, fmt.Sprintf("Report %d", i), 1000))
+		}
+	}
+	// One of them suddenly sends ten times as many mails, the other one a single large mail
+	var current sglog.MailData
+	for i := 0; i < 20; i++ {
+		current.Append(testMail("a@example.com", "b@else.example.org", "2020-07-21", fmt.Sprintf("10:%02d:00", i), fmt.Sprintf("Report %d", i), 1000))
+	}
+	current.Append(testMail("c@example.com", "d@else.example.org", "2020-07-21", "10:00:00", "Report 0", 1000))
+	current.Append(testMail("c@example.com", "d@else.example.org", "2020-07-21", "14:00:00", "Report 1", 1000))
+	current.Append(testMail("e@example.com", "d@else.example.org", "2020-07-21", "10:00:00", "Hello", 1000))
+
+	ar := newAnomalyReport(&current, &baseline, "day", 3)
+	if ar.BaselineFrom != "2020-07-11" || ar.BaselineTo != "2020-07-20" || ar.BaselineBuckets != 10 {
+		t.Errorf("newAnomalyReport() baseline = %s to %s in %d buckets", ar.BaselineFrom, ar.BaselineTo, ar.BaselineBuckets)
+	}
+	// The score is the increase over the mean in standard deviations, which are at least the square root of the mean.
+	score := math.Round((20-2)/math.Sqrt(2)*100) / 100
+	want := []anomalyEntry{
+		{Kind: "partner", Key: "b@else.example.org <-> a@example.com", Type: "e2i", Bucket: "2020-07-21", Metric: "mails", Value: 20, Baseline: 2, Score: score},
+		{Kind: "route", Key: "a@example.com -> else.example.org", Type: "i2e", Bucket: "2020-07-21", Metric: "mails", Value: 20, Baseline: 2, Score: score},
+		{Kind: "sender", Key: "a@example.com", Type: "internal", Bucket: "2020-07-21", Metric: "mails", Value: 20, Baseline: 2, Score: score},
+	}
+	if !reflect.DeepEqual(ar.Anomalies, want) {
+		t.Errorf("newAnomalyReport() = %+v, want %+v", ar.Anomalies, want)
+	}
+
+	if lenient := newAnomalyReport(&current, &baseline, "day", 20); len(lenient.Anomalies) != 0 {
+		t.Errorf("newAnomalyReport() with threshold 20 = %+v, want no anomalies", lenient.Anomalies)
+	}
+}
+
+func TestNewAnomalyReportAverageSize(t *testing.T) {
+	var baseline sglog.MailData
+	for day := 11; day <= 20; day++ {
+		baseline.Append(testMail("a@example.com", "b@else.example.org", fmt.Sprintf("2020-07-%02d", day), "10:00:00", "Report", 1000))
+	}
+	current := testData(testMail("a@example.com", "b@else.example.org", "2020-07-21", "10:00:00", "Report", 100000))
+
+	ar := newAnomalyReport(&current, &baseline, "day", 3)
+	if len(ar.Anomalies) != 3 {
+		t.Fatalf("newAnomalyReport() = %+v, want 3 anomalies", ar.Anomalies)
+	}
+	// Without any deviation in the baseline, the average size is scored against a tenth of its mean.
+	for _, entry := range ar.Anomalies {
+		if entry.Metric != "avgSize" || entry.Value != 100000 || entry.Baseline != 1000 || entry.Score != 980.2 {
+			t.Errorf("newAnomalyReport() %s anomaly = %+v, want avgSize of 100000 against 1000 with score 980.2", entry.Kind, entry)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gitlab.com/rbrt-weiler/sophos-sg-smtp-logparser/pkg/sglog"
+)
+
+var (
+	// Colours used for nodes in graph output, by type
+	commGraphColours = map[string]string{
+		"internal": "#3b7dd8",
+		"external": "#d8613b",
+		"bounce":   "#8a8a8a",
+		"special":  "#9b59b6",
+	}
+	commGraphDefaultColour = "#999999"
+)
+
+// Stores a single node of a commGraph, either an address or a domain.
+// Domains with addresses of different types carry all of these types, joined by "+".
+type commNode struct {
+	ID    string
+	Label string
+	Type  string
+}
+
+// Stores a directed, weighted edge of a commGraph.
+type commEdge struct {
+	Source string
+	Target string
+	Mails  int64
+	Size   int64
+	Weight int64
+}
+
+// commGraph represents the communication between partners as a directed graph.
+type commGraph struct {
+	Nodes []commNode
+	Edges []commEdge
+}
+
+// newCommGraph creates a commGraph from all mailPartners in md.
+// If byDomain is true, nodes represent domains instead of addresses. Edges are weighted by mail count or, if weightBy is "size", by bytes.
+func newCommGraph(md *sglog.MailData, byDomain bool, weightBy string) commGraph {
+	nodes := make(map[string]commNode)
+	edges := make(map[string]*commEdge)
+
+	addNode := func(id string, nodeType string) {
+		node, ok := nodes[id]
+		if !ok {
+			nodes[id] = commNode{ID: id, Label: id, Type: nodeType}
+			return
+		}
+		types := strings.Split(node.Type, "+")
+		for _, known := range types {
+			if known == nodeType {
+				return
+			}
+		}
+		types = append(types, nodeType)
+		sort.Strings(types)
+		node.Type = strings.Join(types, "+")
+		nodes[id] = node
+	}
+
+	addEdge := func(source string, target string, mails int64, size int64) {
+		if mails < 1 {
+			return
+		}
+		key := source + " " + target
+		edge, ok := edges[key]
+		if !ok {
+			edge = &commEdge{Source: source, Target: target}
+			edges[key] = edge
+		}
+		edge.Mails = edge.Mails + mails
+		edge.Size = edge.Size + size
+	}
+
+	for _, k := range sortedPartnerKeys(md) {
+		mp := md.Partner[k]
+		nodeA, nodeB := mp.PartnerA, mp.PartnerB
+		if byDomain {
+			nodeA, nodeB = displayHost(mp.HostA, mp.PartnerA), displayHost(mp.HostB, mp.PartnerB)
+		}
+		addNode(nodeA, mp.TypeA)
+		addNode(nodeB, mp.TypeB)
+		addEdge(nodeA, nodeB, mp.MailsAtoB, mp.SizeAtoB)
+		addEdge(nodeB, nodeA, mp.MailsBtoA, mp.SizeBtoA)
+	}
+
+	var cg commGraph
+	for _, node := range nodes {
+		cg.Nodes = append(cg.Nodes, node)
+	}
+	sort.Slice(cg.Nodes, func(i, j int) bool {
+		return cg.Nodes[i].ID < cg.Nodes[j].ID
+	})
+	for _, edge := range edges {
+		edge.Weight = edge.Mails
+		if weightBy == "size" {
+			edge.Weight = edge.Size
+		}
+		cg.Edges = append(cg.Edges, *edge)
+	}
+	sort.Slice(cg.Edges, func(i, j int) bool {
+		if cg.Edges[i].Source != cg.Edges[j].Source {
+			return cg.Edges[i].Source < cg.Edges[j].Source
+		}
+		return cg.Edges[i].Target < cg.Edges[j].Target
+	})
+	return cg
+}
+
+// colour returns the colour of a commNode based on its type. Nodes of mixed types get the default colour.
+func (cn *commNode) colour() string {
+	if colour, ok := commGraphColours[cn.Type]; ok {
+		return colour
+	}
+	return commGraphDefaultColour
+}
+
+// ToDOT returns a Graphviz DOT representation of a commGraph object.
+func (cg *commGraph) ToDOT() string {
+	quote := func(value string) string {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+	}
+	var sb strings.Builder
+	sb.WriteString("digraph mails {\n")
+	sb.WriteString("\tnode [shape=box, style=filled, fontcolor=white];\n")
+	for _, node := range cg.Nodes {
+		fmt.Fprintf(&sb, "\t%s [label=%s, fillcolor=%s, type=%s];\n", quote(node.ID), quote(node.Label), quote(node.colour()), quote(node.Type))
+	}
+	for _, edge := range cg.Edges {
+		label := fmt.Sprintf("%d mails, %s", edge.Mails, formatBytes(edge.Size))
+		fmt.Fprintf(&sb, "\t%s -> %s [label=%s, weight=%d, mails=%d, size=%d];\n", quote(edge.Source), quote(edge.Target), quote(label), edge.Weight, edge.Mails, edge.Size)
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// Structures used for encoding a commGraph as GEXF.
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfAttValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type gexfColour struct {
+	R uint8 `xml:"r,attr"`
+	G uint8 `xml:"g,attr"`
+	B uint8 `xml:"b,attr"`
+}
+
+type gexfNode struct {
+	ID        string         `xml:"id,attr"`
+	Label     string         `xml:"label,attr"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+	Colour    gexfColour     `xml:"viz:color"`
+}
+
+type gexfEdge struct {
+	ID        string         `xml:"id,attr"`
+	Source    string         `xml:"source,attr"`
+	Target    string         `xml:"target,attr"`
+	Weight    int64          `xml:"weight,attr"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+}
+
+type gexfGraph struct {
+	EdgeType   string           `xml:"defaultedgetype,attr"`
+	Attributes []gexfAttributes `xml:"attributes"`
+	Nodes      []gexfNode       `xml:"nodes>node"`
+	Edges      []gexfEdge       `xml:"edges>edge"`
+}
+
+type gexfDocument struct {
+	XMLName   xml.Name  `xml:"gexf"`
+	Namespace string    `xml:"xmlns,attr"`
+	VizNS     string    `xml:"xmlns:viz,attr"`
+	Version   string    `xml:"version,attr"`
+	Creator   string    `xml:"meta>creator"`
+	Graph     gexfGraph `xml:"graph"`
+}
+
+// ToGEXF returns a GEXF representation of a commGraph object, suitable for Gephi.
+// Mail count and size are stored as additional edge attributes.
+func (cg *commGraph) ToGEXF() string {
+	doc := gexfDocument{
+		Namespace: "http://gexf.net/1.3",
+		VizNS:     "http://gexf.net/1.3/viz",
+		Version:   "1.3",
+		Creator:   toolID,
+		Graph: gexfGraph{
+			EdgeType: "directed",
+			Attributes: []gexfAttributes{
+				{Class: "node", Attributes: []gexfAttribute{{ID: "type", Title: "type", Type: "string"}}},
+				{Class: "edge", Attributes: []gexfAttribute{{ID: "mails", Title: "mails", Type: "long"}, {ID: "size", Title: "size", Type: "long"}}},
+			},
+		},
+	}
+	for _, node := range cg.Nodes {
+		var colour gexfColour
+		fmt.Sscanf(node.colour(), "#%02x%02x%02x", &colour.R, &colour.G, &colour.B)
+		doc.Graph.Nodes = append(doc.Graph.Nodes, gexfNode{
+			ID:        node.ID,
+			Label:     node.Label,
+			AttValues: []gexfAttValue{{For: "type", Value: node.Type}},
+			Colour:    colour,
+		})
+	}
+	for i, edge := range cg.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, gexfEdge{
+			ID:     fmt.Sprintf("e%d", i),
+			Source: edge.Source,
+			Target: edge.Target,
+			Weight: edge.Weight,
+			AttValues: []gexfAttValue{
+				{For: "mails", Value: fmt.Sprintf("%d", edge.Mails)},
+				{For: "size", Value: fmt.Sprintf("%d", edge.Size)},
+			},
+		})
+	}
+	content, _ := xml.MarshalIndent(doc, "", "    ")
+	return xml.Header + string(content) + "\n"
+}
+
+// Structures used for encoding a commGraph as GraphML.
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLDocument struct {
+	XMLName   xml.Name     `xml:"graphml"`
+	Namespace string       `xml:"xmlns,attr"`
+	Keys      []graphMLKey `xml:"key"`
+	Graph     graphMLGraph `xml:"graph"`
+}
+
+// ToGraphML returns a GraphML representation of a commGraph object.
+func (cg *commGraph) ToGraphML() string {
+	doc := graphMLDocument{
+		Namespace: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "label", For: "node", AttrName: "label", AttrType: "string"},
+			{ID: "type", For: "node", AttrName: "type", AttrType: "string"},
+			{ID: "color", For: "node", AttrName: "color", AttrType: "string"},
+			{ID: "weight", For: "edge", AttrName: "weight", AttrType: "long"},
+			{ID: "mails", For: "edge", AttrName: "mails", AttrType: "long"},
+			{ID: "size", For: "edge", AttrName: "size", AttrType: "long"},
+		},
+		Graph: graphMLGraph{ID: "mails", EdgeDefault: "directed"},
+	}
+	for _, node := range cg.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: node.ID,
+			Data: []graphMLData{
+				{Key: "label", Value: node.Label},
+				{Key: "type", Value: node.Type},
+				{Key: "color", Value: node.colour()},
+			},
+		})
+	}
+	for i, edge := range cg.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			ID:     fmt.Sprintf("e%d", i),
+			Source: edge.Source,
+			Target: edge.Target,
+			Data: []graphMLData{
+				{Key: "weight", Value: fmt.Sprintf("%d", edge.Weight)},
+				{Key: "mails", Value: fmt.Sprintf("%d", edge.Mails)},
+				{Key: "size", Value: fmt.Sprintf("%d", edge.Size)},
+			},
+		})
+	}
+	content, _ := xml.MarshalIndent(doc, "", "    ")
+	return xml.Header + string(content) + "\n"
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewCommGraph(t *testing.T) {
+	question := testMail("a@example.com", "b@else.example.org", "2020-07-18", "10:00:00", "One", 10)
+	answer := testMail("b@else.example.org", "a@example.com", "2020-07-18", "11:00:00", "Re: One", 20)
+	report := testMail("postmaster@example.com", "b@else.example.org", "2020-07-18", "12:00:00", "Report", 40)
+	report.SetTypeFrom("special")
+
+	tests := []struct {
+		name      string
+		byDomain  bool
+		weightBy  string
+		wantNodes []commNode
+		wantEdges []commEdge
+	}{
+		{"addresses by count", false, "count",
+			[]commNode{{"a@example.com", "a@example.com", "internal"}, {"b@else.example.org", "b@else.example.org", "external"}, {"postmaster@example.com", "postmaster@example.com", "special"}},
+			[]commEdge{{"a@example.com", "b@else.example.org", 1, 10, 1}, {"b@else.example.org", "a@example.com", 1, 20, 1}, {"postmaster@example.com", "b@else.example.org", 1, 40, 1}}},
+		{"domains by size", true, "size",
+			[]commNode{{"else.example.org", "else.example.org", "external"}, {"example.com", "example.com", "internal+special"}},
+			[]commEdge{{"else.example.org", "example.com", 1, 20, 20}, {"example.com", "else.example.org", 2, 50, 50}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md := testData(question, answer, report)
+			cg := newCommGraph(&md, tt.byDomain, tt.weightBy)
+			if !reflect.DeepEqual(cg.Nodes, tt.wantNodes) {
+				t.Errorf("newCommGraph() nodes = %+v, want %+v", cg.Nodes, tt.wantNodes)
+			}
+			if !reflect.DeepEqual(cg.Edges, tt.wantEdges) {
+				t.Errorf("newCommGraph() edges = %+v, want %+v", cg.Edges, tt.wantEdges)
+			}
+		})
+	}
+}
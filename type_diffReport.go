@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gitlab.com/rbrt-weiler/sophos-sg-smtp-logparser/pkg/sglog"
+)
+
+var (
+	// Format strings for CSV output
+	diffReportCSVHeader = "change,type,partnerA,partnerB,mailsBefore,mailsAfter,sizeBefore,sizeAfter,isTwoWayBefore,isTwoWayAfter"
+	diffReportCSVFormat = "%s,%s,%s,%s,%d,%d,%d,%d,%t,%t"
+
+	// Kinds of changes in the order they are reported
+	diffChanges = []string{"new", "disappeared", "changed", "two-way", "one-way"}
+)
+
+// Stores a single change of a communication partner between two periods.
+type diffEntry struct {
+	Change       string `json:"change"`
+	Type         string `json:"type"`
+	PartnerA     string `json:"partnerA"`
+	PartnerB     string `json:"partnerB"`
+	MailsBefore  int64  `json:"mailsBefore"`
+	MailsAfter   int64  `json:"mailsAfter"`
+	SizeBefore   int64  `json:"sizeBefore"`
+	SizeAfter    int64  `json:"sizeAfter"`
+	TwoWayBefore bool   `json:"isTwoWayBefore"`
+	TwoWayAfter  bool   `json:"isTwoWayAfter"`
+}
+
+// Stores the number of changes of each kind for a single communication type.
+type diffTotal struct {
+	Type        string `json:"type"`
+	New         int64  `json:"new"`
+	Disappeared int64  `json:"disappeared"`
+	Changed     int64  `json:"changed"`
+	TwoWay      int64  `json:"twoWay"`
+	OneWay      int64  `json:"oneWay"`
+}
+
+// Stores all changes of communication partners between two periods.
+type diffReport struct {
+	Threshold float64     `json:"threshold"`
+	Before    string      `json:"before"`
+	After     string      `json:"after"`
+	Types     []diffTotal `json:"types"`
+	Changes   []diffEntry `json:"changes"`
+}
+
+// newDiffReport compares the mailPartners in before and after.
+// Partners are considered changed if their mail count or size changed by more than threshold, relative to before (e.g. 0.5 for 50%).
+func newDiffReport(before *sglog.MailData, after *sglog.MailData, beforeName string, afterName string, threshold float64) diffReport {
+	dr := diffReport{Threshold: threshold, Before: beforeName, After: afterName, Types: []diffTotal{}, Changes: []diffEntry{}}
+	totals := make(map[string]*diffTotal)
+	add := func(change string, beforePartner sglog.MailPartner, afterPartner sglog.MailPartner) {
+		entry := diffEntry{
+			Change:       change,
+			Type:         afterPartner.Type,
+			PartnerA:     afterPartner.PartnerA,
+			PartnerB:     afterPartner.PartnerB,
+			MailsBefore:  beforePartner.MailsTotal,
+			MailsAfter:   afterPartner.MailsTotal,
+			SizeBefore:   beforePartner.SizeTotal,
+			SizeAfter:    afterPartner.SizeTotal,
+			TwoWayBefore: beforePartner.IsTwoWay,
+			TwoWayAfter:  afterPartner.IsTwoWay,
+		}
+		if change == "disappeared" {
+			entry.Type, entry.PartnerA, entry.PartnerB = beforePartner.Type, beforePartner.PartnerA, beforePartner.PartnerB
+		}
+		dr.Changes = append(dr.Changes, entry)
+		if _, ok := totals[entry.Type]; !ok {
+			totals[entry.Type] = &diffTotal{Type: entry.Type}
+		}
+		total := totals[entry.Type]
+		switch change {
+		case "new":
+			total.New++
+		case "disappeared":
+			total.Disappeared++
+		case "changed":
+			total.Changed++
+		case "two-way":
+			total.TwoWay++
+		case "one-way":
+			total.OneWay++
+		}
+	}
+
+	for key, afterPartner := range after.Partner {
+		beforePartner, existed := before.Partner[key]
+		if !existed {
+			add("new", beforePartner, afterPartner)
+			continue
+		}
+		if exceedsThreshold(beforePartner.MailsTotal, afterPartner.MailsTotal, threshold) || exceedsThreshold(beforePartner.SizeTotal, afterPartner.SizeTotal, threshold) {
+			add("changed", beforePartner, afterPartner)
+		}
+		if !beforePartner.IsTwoWay && afterPartner.IsTwoWay {
+			add("two-way", beforePartner, afterPartner)
+		} else if beforePartner.IsTwoWay && !afterPartner.IsTwoWay {
+			add("one-way", beforePartner, afterPartner)
+		}
+	}
+	for key, beforePartner := range before.Partner {
+		if _, exists := after.Partner[key]; !exists {
+			add("disappeared", beforePartner, sglog.MailPartner{})
+		}
+	}
+
+	order := make(map[string]int)
+	for i, change := range diffChanges {
+		order[change] = i
+	}
+	sort.Slice(dr.Changes, func(i, j int) bool {
+		a, b := dr.Changes[i], dr.Changes[j]
+		if a.Change != b.Change {
+			return order[a.Change] < order[b.Change]
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.MailsAfter+a.MailsBefore != b.MailsAfter+b.MailsBefore {
+			return a.MailsAfter+a.MailsBefore > b.MailsAfter+b.MailsBefore
+		}
+		return a.PartnerA+" "+a.PartnerB < b.PartnerA+" "+b.PartnerB
+	})
+	for _, total := range totals {
+		dr.Types = append(dr.Types, *total)
+	}
+	sort.Slice(dr.Types, func(i, j int) bool {
+		return dr.Types[i].Type < dr.Types[j].Type
+	})
+	return dr
+}
+
+// exceedsThreshold returns true if after differs from before by more than threshold, relative to before.
+func exceedsThreshold(before int64, after int64, threshold float64) bool {
+	if before == after {
+		return false
+	}
+	if before == 0 {
+		return true
+	}
+	change := float64(after-before) / float64(before)
+	if change < 0 {
+		change = -change
+	}
+	return change > threshold
+}
+
+// ToCSV returns a CSV representation of a diffReport object.
+func (dr *diffReport) ToCSV(withHeader bool) string {
+	var lines []string
+	if withHeader {
+		lines = append(lines, diffReportCSVHeader)
+	}
+	for _, entry := range dr.Changes {
+		lines = append(lines, fmt.Sprintf(diffReportCSVFormat, entry.Change, entry.Type, entry.PartnerA, entry.PartnerB, entry.MailsBefore, entry.MailsAfter, entry.SizeBefore, entry.SizeAfter, entry.TwoWayBefore, entry.TwoWayAfter))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ToTable returns a human-readable representation of a diffReport object.
+func (dr *diffReport) ToTable() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Changes from %s to %s\n\n", dr.Before, dr.After)
+
+	var types textTable
+	types.SetHeader("type", "new", "disappeared", "changed", "two-way", "one-way")
+	types.AlignRight(1, 2, 3, 4, 5)
+	for _, total := range dr.Types {
+		types.AddRow(total.Type, formatCount(total.New), formatCount(total.Disappeared), formatCount(total.Changed), formatCount(total.TwoWay), formatCount(total.OneWay))
+	}
+	sb.WriteString(types.String())
+	sb.WriteString("\n")
+
+	titles := map[string]string{
+		"new":         "New partners",
+		"disappeared": "Disappeared partners",
+		"changed":     fmt.Sprintf("Partners with more than %.0f%% change in mails or bytes", dr.Threshold*100),
+		"two-way":     "Partners that became two-way",
+		"one-way":     "Partners that are no longer two-way",
+	}
+	for _, change := range diffChanges {
+		var tt textTable
+		tt.SetHeader("type", "partner", "mails before", "mails after", "bytes before", "bytes after")
+		tt.AlignRight(2, 3, 4, 5)
+		for _, entry := range dr.Changes {
+			if entry.Change != change {
+				continue
+			}
+			tt.AddRow(entry.Type, entry.PartnerA+" <-> "+entry.PartnerB, formatCount(entry.MailsBefore), formatCount(entry.MailsAfter), formatBytes(entry.SizeBefore), formatBytes(entry.SizeAfter))
+		}
+		if tt.Len() == 0 {
+			continue
+		}
+		sb.WriteString(titles[change])
+		sb.WriteString("\n\n")
+		sb.WriteString(tt.String())
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"gitlab.com/rbrt-weiler/sophos-sg-smtp-logparser/pkg/sglog"
+)
+
+func TestExceedsThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		before    int64
+		after     int64
+		threshold float64
+		want      bool
+	}{
+		{"unchanged", 10, 10, 0.5, false},
+		{"unchanged without threshold", 10, 10, 0, false},
+		{"new", 0, 1, 0.5, true},
+		{"increase below threshold", 10, 14, 0.5, false},
+		{"increase at threshold", 10, 15, 0.5, false},
+		{"increase above threshold", 10, 16, 0.5, true},
+		{"decrease at threshold", 10, 5, 0.5, false},
+		{"decrease above threshold", 10, 4, 0.5, true},
+		{"gone", 10, 0, 0.5, true},
+		{"gone with threshold of 100%", 10, 0, 1, false},
+		{"any change without threshold", 10, 11, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exceedsThreshold(tt.before, tt.after, tt.threshold); got != tt.want {
+				t.Errorf("exceedsThreshold(%d, %d, %v) = %v, want %v", tt.before, tt.after, tt.threshold, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewDiffReport(t *testing.T) {
+	before := testData(
+		testMail("a@example.com", "b@else.example.org", "2020-07-18", "10:00:00", "One", 10),
+		testMail("a@example.com", "b@else.example.org", "2020-07-18", "11:00:00", "Two", 10),
+		testMail("a@example.com", "c@else.example.org", "2020-07-18", "10:00:00", "One", 10),
+		testMail("c@else.example.org", "a@example.com", "2020-07-18", "12:00:00", "Re: One", 10),
+		testMail("a@example.com", "d@else.example.org", "2020-07-18", "10:00:00", "One", 10),
+	)
+	after := testData(
+		testMail("a@example.com", "b@else.example.org", "2020-07-25", "10:00:00", "One", 10),
+		testMail("a@example.com", "b@else.example.org", "2020-07-25", "11:00:00", "Two", 10),
+		testMail("a@example.com", "b@else.example.org", "2020-07-25", "12:00:00", "Three", 10),
+		testMail("b@else.example.org", "a@example.com", "2020-07-25", "13:00:00", "Re: Three", 10),
+		testMail("a@example.com", "c@else.example.org", "2020-07-25", "10:00:00", "One", 10),
+		testMail("e@example.com", "a@example.com", "2020-07-25", "10:00:00", "One", 10),
+	)
+	dr := newDiffReport(&before, &after, "before.json", "after.json", 0.5)
+
+	var got []string
+	for _, entry := range dr.Changes {
+		got = append(got, entry.Change+" "+entry.PartnerA+" "+entry.PartnerB)
+	}
+	want := []string{
+		"new a@example.com e@example.com",
+		"disappeared d@else.example.org a@example.com",
+		"changed b@else.example.org a@example.com",
+		"two-way b@else.example.org a@example.com",
+		"one-way c@else.example.org a@example.com",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("newDiffReport() changes = %q, want %q", got, want)
+	}
+	wantTypes := []diffTotal{{Type: "e2i", Disappeared: 1, Changed: 1, TwoWay: 1, OneWay: 1}, {Type: "i2i", New: 1}}
+	if !reflect.DeepEqual(dr.Types, wantTypes) {
+		t.Errorf("newDiffReport() types = %+v, want %+v", dr.Types, wantTypes)
+	}
+
+	same := newDiffReport(&before, &before, "before.json", "before.json", 0)
+	if len(same.Changes) != 0 {
+		t.Errorf("newDiffReport() of identical data = %+v, want no changes", same.Changes)
+	}
+	var empty sglog.MailData
+	if gone := newDiffReport(&before, &empty, "before.json", "empty.json", 0.5); len(gone.Changes) != 3 {
+		t.Errorf("newDiffReport() against empty data has %d changes, want 3", len(gone.Changes))
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"gitlab.com/rbrt-weiler/sophos-sg-smtp-logparser/pkg/sglog"
+)
+
+const (
+	elasticBatchSize int = 5000 // Number of mails sent in a single _bulk request
+)
+
+// Stores a single mail as Elasticsearch document.
+type elasticDocument struct {
+	Timestamp string `json:"@timestamp"`
+	Type      string `json:"type"`
+	sglog.SingleMail
+}
+
+// Stores the target of a single document in an Elasticsearch _bulk request.
+type elasticAction struct {
+	Index struct {
+		Index string `json:"_index"`
+		ID    string `json:"_id"`
+	} `json:"index"`
+}
+
+// Stores all mails as action and document lines of the Elasticsearch _bulk API.
+type elasticExport struct {
+	Lines []string
+}
+
+// newElasticExport creates an elasticExport from all mails in md, ordered by date and time.
+// The index of every mail is created from indexPattern, see expandIndexPattern. The mailID is used as document ID, so repeated imports do not create duplicates.
+// Mails are considered to be logged in location, which sets the offset of their timestamps.
+func newElasticExport(md *sglog.MailData, indexPattern string, location *time.Location) elasticExport {
+	var ee elasticExport
+	for _, mail := range sortedMails(md) {
+		var action elasticAction
+		action.Index.Index = expandIndexPattern(indexPattern, mail.Date, mail.Time)
+		action.Index.ID = mail.MailID
+		document := elasticDocument{Timestamp: mail.Date + "T" + mail.Time, Type: mail.GetType(), SingleMail: mail}
+		if t, parseErr := time.ParseInLocation("2006-01-02 15:04:05", mail.Date+" "+mail.Time, location); parseErr == nil {
+			document.Timestamp = t.Format(time.RFC3339)
+		}
+		actionJSON, _ := json.Marshal(action)
+		documentJSON, _ := json.Marshal(document)
+		ee.Lines = append(ee.Lines, string(actionJSON), string(documentJSON))
+	}
+	return ee
+}
+
+// expandIndexPattern replaces %Y, %m, %d and %H in pattern with year, month, day and hour of a mail sent on date (YYYY-MM-DD) at clock (HH:MM:SS).
+// %% is replaced with a single %.
+func expandIndexPattern(pattern string, date string, clock string) string {
+	year, month, day, hour := "0000", "00", "00", "00"
+	if parts := strings.Split(date, "-"); len(parts) == 3 {
+		year, month, day = parts[0], parts[1], parts[2]
+	}
+	if len(clock) >= 2 {
+		hour = clock[:2]
+	}
+	replacer := strings.NewReplacer("%Y", year, "%m", month, "%d", day, "%H", hour, "%%", "%")
+	return replacer.Replace(pattern)
+}
+
+// Batches returns the NDJSON bodies of _bulk requests containing up to size mails each.
+func (ee *elasticExport) Batches(size int) []string {
+	var batches []string
+	for start := 0; start < len(ee.Lines); start += 2 * size {
+		end := start + 2*size
+		if end > len(ee.Lines) {
+			end = len(ee.Lines)
+		}
+		batches = append(batches, strings.Join(ee.Lines[start:end], "\n")+"\n")
+	}
+	return batches
+}
+
+// ToNDJSON returns the body of a single _bulk request containing all mails.
+func (ee *elasticExport) ToNDJSON() string {
+	return strings.Join(ee.Lines, "\n")
+}
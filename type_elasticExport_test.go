@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/rbrt-weiler/sophos-sg-smtp-logparser/pkg/sglog"
+)
+
+func TestNewElasticExportTimestamp(t *testing.T) {
+	var mail sglog.SingleMail
+	mail.SetDate("2020-07-18")
+	mail.SetTime("16:56:31")
+	mail.SetFrom("a@example.com")
+	mail.SetTo("b@else.example.org")
+	mail.SetTypeFrom("internal")
+	mail.SetTypeTo("external")
+	mail.SetQueueID("q")
+	mail.GenerateMailID()
+	var md sglog.MailData
+	md.Append(mail)
+
+	tests := []struct {
+		name     string
+		location *time.Location
+		want     string
+	}{
+		{"UTC", time.UTC, `"@timestamp":"2020-07-18T16:56:31Z"`},
+		{"summer time", time.FixedZone("CEST", 2*60*60), `"@timestamp":"2020-07-18T16:56:31+02:00"`},
+		{"negative offset", time.FixedZone("EDT", -4*60*60), `"@timestamp":"2020-07-18T16:56:31-04:00"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ee := newElasticExport(&md, "mail-%Y.%m.%d", tt.location)
+			if len(ee.Lines) != 2 {
+				t.Fatalf("newElasticExport() has %d lines, want 2", len(ee.Lines))
+			}
+			if !strings.Contains(ee.Lines[0], `"_index":"mail-2020.07.18"`) {
+				t.Errorf("action = %s", ee.Lines[0])
+			}
+			if !strings.Contains(ee.Lines[1], tt.want) {
+				t.Errorf("document = %s, want %s", ee.Lines[1], tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gitlab.com/rbrt-weiler/sophos-sg-smtp-logparser/pkg/sglog"
+)
+
+var (
+	// Format strings for CSV output
+	hoursReportCSVHeader = "category,type,key,mailsInHours,sizeInHours,mailsOffHours,sizeOffHours"
+	hoursReportCSVFormat = "%s,%s,%s,%d,%d,%d,%d"
+)
+
+// Stores the mail volume of a single sender or partner in and outside of working hours.
+type hoursEntry struct {
+	Type          string `json:"type"`
+	Key           string `json:"key"`
+	MailsInHours  int64  `json:"mailsInHours"`
+	SizeInHours   int64  `json:"sizeInHours"`
+	MailsOffHours int64  `json:"mailsOffHours"`
+	SizeOffHours  int64  `json:"sizeOffHours"`
+}
+
+// Stores the mail volume of all senders and partners in and outside of working hours.
+type hoursReport struct {
+	Senders  []hoursEntry `json:"senders"`
+	Partners []hoursEntry `json:"partners"`
+}
+
+// newHoursReport creates an hoursReport from all mails stored in md, which must have been classified by a sglog.Calendar.
+func newHoursReport(md *sglog.MailData) hoursReport {
+	senders := make(map[string]*hoursEntry)
+	hr := hoursReport{Partners: []hoursEntry{}}
+	for partnerKey, partner := range md.Partner {
+		hr.Partners = append(hr.Partners, hoursEntry{
+			Type:          partner.Type,
+			Key:           partnerKey,
+			MailsInHours:  partner.MailsTotal - partner.MailsOffHours,
+			SizeInHours:   partner.SizeTotal - partner.SizeOffHours,
+			MailsOffHours: partner.MailsOffHours,
+			SizeOffHours:  partner.SizeOffHours,
+		})
+		for _, mail := range partner.Mails {
+			sender, ok := senders[mail.From]
+			if !ok {
+				sender = &hoursEntry{Type: mail.TypeFrom, Key: mail.From}
+				senders[mail.From] = sender
+			}
+			if mail.IsOffHours {
+				sender.MailsOffHours++
+				sender.SizeOffHours = sender.SizeOffHours + mail.Size
+			} else {
+				sender.MailsInHours++
+				sender.SizeInHours = sender.SizeInHours + mail.Size
+			}
+		}
+	}
+	hr.Senders = make([]hoursEntry, 0, len(senders))
+	for _, sender := range senders {
+		hr.Senders = append(hr.Senders, *sender)
+	}
+	sortHoursEntries(hr.Senders)
+	sortHoursEntries(hr.Partners)
+	return hr
+}
+
+// sortHoursEntries sorts entries by off-hours mails, off-hours size and finally by key.
+func sortHoursEntries(entries []hoursEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.MailsOffHours != b.MailsOffHours {
+			return a.MailsOffHours > b.MailsOffHours
+		}
+		if a.SizeOffHours != b.SizeOffHours {
+			return a.SizeOffHours > b.SizeOffHours
+		}
+		return a.Key < b.Key
+	})
+}
+
+// ToCSV returns a CSV representation of an hoursReport object.
+func (hr *hoursReport) ToCSV(withHeader bool) string {
+	var lines []string
+	if withHeader {
+		lines = append(lines, hoursReportCSVHeader)
+	}
+	for i, entries := range [][]hoursEntry{hr.Senders, hr.Partners} {
+		category := []string{"senders", "partners"}[i]
+		for _, entry := range entries {
+			lines = append(lines, fmt.Sprintf(hoursReportCSVFormat, category, entry.Type, entry.Key, entry.MailsInHours, entry.SizeInHours, entry.MailsOffHours, entry.SizeOffHours))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ToTable returns a human-readable representation of an hoursReport object.
+func (hr *hoursReport) ToTable() string {
+	var sb strings.Builder
+	for i, entries := range [][]hoursEntry{hr.Senders, hr.Partners} {
+		name := []string{"sender", "partner"}[i]
+		fmt.Fprintf(&sb, "Mails in and outside of working hours by %s\n\n", name)
+		var tt textTable
+		tt.SetHeader("type", name, "mails in hours", "bytes in hours", "mails off hours", "bytes off hours")
+		tt.AlignRight(2, 3, 4, 5)
+		for _, entry := range entries {
+			tt.AddRow(entry.Type, entry.Key, formatCount(entry.MailsInHours), formatBytes(entry.SizeInHours), formatCount(entry.MailsOffHours), formatBytes(entry.SizeOffHours))
+		}
+		sb.WriteString(tt.String())
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
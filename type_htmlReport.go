@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+	"time"
+
+	"gitlab.com/rbrt-weiler/sophos-sg-smtp-logparser/pkg/sglog"
+)
+
+const (
+	htmlTimelineWidth  int = 1000 // Width of the timeline chart in pixels
+	htmlTimelineHeight int = 200  // Height of the timeline chart in pixels
+	htmlTimelineLabels int = 12   // Maximum number of labels below the timeline chart
+	htmlTimelineSlot   int = 3    // Minimum width of a bar in the timeline chart including its gap, in pixels
+)
+
+var (
+	// Units of the timeline chart from the shortest to the longest, along with the format of their labels
+	htmlTimelineUnits        = []string{"hour", "day", "week", "month", "year"}
+	htmlTimelineLabelFormats = map[string]string{"hour": "15:00", "day": "2006-01-02", "week": "2006-01-02", "month": "2006-01", "year": "2006"}
+)
+
+// Stores a single bar of the timeline chart in a htmlReport.
+type htmlTimelineBar struct {
+	Label     string
+	Mails     int64
+	Size      int64
+	X         int
+	Y         int
+	Width     int
+	Height    int
+	LabelX    int
+	ShowLabel bool
+}
+
+// Stores the timeline chart of a htmlReport.
+type htmlTimeline struct {
+	Unit   string
+	Width  int
+	Height int
+	LabelY int
+	Max    int64
+	Bars   []htmlTimelineBar
+}
+
+// Stores a MailPartner along with an identifier that is used for linking within a htmlReport.
+type htmlPartner struct {
+	sglog.MailPartner
+	ID string
+}
+
+// Stores all data that is rendered into a self-contained HTML report.
+type htmlReport struct {
+	Title     string
+	Tool      string
+	Created   string
+	FirstSeen string
+	LastSeen  string
+	Types     []summaryTotal
+	Timeline  htmlTimeline
+	Partners  []htmlPartner
+}
+
+// newHTMLReport creates a htmlReport from all mails stored in md and the statistics in ps.
+func newHTMLReport(md *sglog.MailData, ps *sglog.ParseStats, title string) htmlReport {
+	sr := newSummaryReport(md, ps)
+	hr := htmlReport{
+		Title:     title,
+		Tool:      toolID,
+		Created:   md.CreateDateTime.Format("2006-01-02 15:04:05"),
+		FirstSeen: sr.FirstSeen,
+		LastSeen:  sr.LastSeen,
+		Types:     sr.Types,
+	}
+
+	for i, k := range sortedPartnerKeys(md) {
+		partner := md.Partner[k]
+		mails := make([]sglog.SingleMail, len(partner.Mails))
+		copy(mails, partner.Mails)
+		sort.SliceStable(mails, func(a, b int) bool {
+			return mails[a].Date+mails[a].Time < mails[b].Date+mails[b].Time
+		})
+		partner.Mails = mails
+		hr.Partners = append(hr.Partners, htmlPartner{MailPartner: partner, ID: fmt.Sprintf("partner-%d", i+1)})
+	}
+
+	hr.Timeline = newHTMLTimeline(md, sr.FirstSeen, sr.LastSeen)
+	return hr
+}
+
+// newHTMLTimeline computes a bar chart of the mails in md between first and last.
+// Mails are grouped by hour if all mails were seen on the same day, else by the shortest of day, week, month and year that keeps every bar at least htmlTimelineSlot pixels wide.
+// If even years would result in too many bars, the chart is left empty.
+func newHTMLTimeline(md *sglog.MailData, first string, last string) htmlTimeline {
+	tl := htmlTimeline{Unit: "day", Width: htmlTimelineWidth, Height: htmlTimelineHeight, LabelY: htmlTimelineHeight - 2}
+	start, startErr := time.Parse("2006-01-02 15:04:05", first)
+	end, endErr := time.Parse("2006-01-02 15:04:05", last)
+	if startErr != nil || endErr != nil {
+		return tl
+	}
+	units := htmlTimelineUnits[1:]
+	if first[:10] == last[:10] {
+		units = htmlTimelineUnits
+	}
+
+	maxBars := tl.Width / htmlTimelineSlot
+	var unit string
+	var keys []time.Time
+	for _, unit = range units {
+		keys = nil
+		for t := timelineStart(unit, start); !t.After(end) && len(keys) <= maxBars; t = timelineNext(unit, t) {
+			keys = append(keys, t)
+		}
+		if len(keys) <= maxBars {
+			break
+		}
+	}
+	tl.Unit = unit
+	if len(keys) > maxBars {
+		return tl
+	}
+
+	bars := make([]*htmlTimelineBar, len(keys))
+	buckets := make(map[string]*htmlTimelineBar)
+	for i, key := range keys {
+		bars[i] = &htmlTimelineBar{Label: key.Format(htmlTimelineLabelFormats[unit])}
+		buckets[key.Format(time.DateTime)] = bars[i]
+	}
+	for _, partner := range md.Partner {
+		for _, mail := range partner.Mails {
+			sent, sentErr := time.Parse("2006-01-02 15:04:05", mail.Date+" "+mail.Time)
+			if sentErr != nil {
+				continue
+			}
+			if bar, ok := buckets[timelineStart(unit, sent).Format(time.DateTime)]; ok {
+				bar.Mails++
+				bar.Size = bar.Size + mail.Size
+			}
+		}
+	}
+
+	for _, bar := range bars {
+		if bar.Mails > tl.Max {
+			tl.Max = bar.Mails
+		}
+	}
+	chartHeight := tl.Height - 30
+	slot := tl.Width / max(len(bars), 1)
+	labelEvery := max(len(bars)/htmlTimelineLabels, 1)
+	for i, bar := range bars {
+		bar.X = i * slot
+		bar.Width = max(slot-2, 1)
+		if tl.Max > 0 {
+			bar.Height = int(bar.Mails * int64(chartHeight) / tl.Max)
+		}
+		bar.Y = 15 + chartHeight - bar.Height
+		bar.LabelX = bar.X + slot/2
+		bar.ShowLabel = i%labelEvery == 0
+		tl.Bars = append(tl.Bars, *bar)
+	}
+	return tl
+}
+
+// timelineStart returns the start of the bar of the timeline chart containing t, with bars being one unit long.
+// Weeks start on Monday.
+func timelineStart(unit string, t time.Time) time.Time {
+	switch unit {
+	case "hour":
+		return t.Truncate(time.Hour)
+	case "week":
+		return time.Date(t.Year(), t.Month(), t.Day()-(int(t.Weekday())+6)%7, 0, 0, 0, 0, time.UTC)
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case "year":
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// timelineNext returns the start of the bar of the timeline chart following the bar starting at t.
+func timelineNext(unit string, t time.Time) time.Time {
+	switch unit {
+	case "hour":
+		return t.Add(time.Hour)
+	case "week":
+		return t.AddDate(0, 0, 7)
+	case "month":
+		return t.AddDate(0, 1, 0)
+	case "year":
+		return t.AddDate(1, 0, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+// ToHTML renders a htmlReport into a single HTML document without external dependencies.
+func (hr *htmlReport) ToHTML() (string, error) {
+	content, readErr := embedFS.ReadFile("embedded-report.html")
+	if readErr != nil {
+		return "", fmt.Errorf("Could not read HTML template: %s", readErr)
+	}
+	tpl, parseErr := template.New("report").Funcs(template.FuncMap{
+		"bytes": formatBytes,
+		"count": formatCount,
+	}).Parse(string(content))
+	if parseErr != nil {
+		return "", fmt.Errorf("Could not parse HTML template: %s", parseErr)
+	}
+	var sb strings.Builder
+	if execErr := tpl.Execute(&sb, hr); execErr != nil {
+		return "", fmt.Errorf("Could not render HTML report: %s", execErr)
+	}
+	return sb.String(), nil
+}
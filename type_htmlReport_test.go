@@ -0,0 +1,46 @@
+package main
+
+import "testing"
+
+func TestNewHTMLTimeline(t *testing.T) {
+	tests := []struct {
+		name     string
+		first    string
+		last     string
+		wantUnit string
+		wantBars int
+	}{
+		{"same day", "2020-07-18 08:15:00", "2020-07-18 17:45:00", "hour", 10},
+		{"two days", "2020-07-18 23:59:59", "2020-07-19 00:00:00", "day", 2},
+		{"one year", "2020-01-01 12:00:00", "2020-12-31 12:00:00", "week", 53},
+		{"ten years", "2011-01-01 12:00:00", "2020-12-31 12:00:00", "month", 120},
+		{"thirty years", "1991-01-01 12:00:00", "2020-12-31 12:00:00", "year", 30},
+		{"four centuries", "1620-01-01 12:00:00", "2020-12-31 12:00:00", "year", 0},
+		{"no mails", "", "", "day", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md := testData()
+			if tt.first != "" {
+				md = testData(
+					testMail("a@example.com", "b@else.example.org", tt.first[:10], tt.first[11:], "First", 10),
+					testMail("a@example.com", "b@else.example.org", tt.last[:10], tt.last[11:], "Last", 20),
+				)
+			}
+			tl := newHTMLTimeline(&md, tt.first, tt.last)
+			if tl.Unit != tt.wantUnit || len(tl.Bars) != tt.wantBars {
+				t.Fatalf("newHTMLTimeline() unit, bars = %q, %d, want %q, %d", tl.Unit, len(tl.Bars), tt.wantUnit, tt.wantBars)
+			}
+			var mails int64
+			for i, bar := range tl.Bars {
+				if bar.Width < 1 || (i > 0 && bar.X <= tl.Bars[i-1].X) || bar.X+bar.Width > tl.Width {
+					t.Errorf("bar %d at x = %d with width %d overlaps its neighbours or the chart border", i, bar.X, bar.Width)
+				}
+				mails = mails + bar.Mails
+			}
+			if tt.wantBars > 0 && (mails != 2 || tl.Bars[0].Mails != 1 || tl.Bars[len(tl.Bars)-1].Mails != 1) {
+				t.Errorf("newHTMLTimeline() counts %d mails, want one in the first and one in the last bar", mails)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gitlab.com/rbrt-weiler/sophos-sg-smtp-logparser/pkg/sglog"
+)
+
+var (
+	// Format strings for CSV output
+	largeMailReportCSVHeader = "date,time,type,from,to,size,subject,mailID"
+	largeMailReportCSVFormat = "%s,%s,%s,%s,%s,%d,%s,%s"
+)
+
+// Stores a single mail of a largeMailReport.
+type largeMailEntry struct {
+	Date    string `json:"date"`
+	Time    string `json:"time"`
+	Type    string `json:"type"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Size    int64  `json:"size"`
+	Subject string `json:"subject"`
+	MailID  string `json:"mailID"`
+}
+
+// Stores all mails of at least a minimum size.
+type largeMailReport struct {
+	MinSize int64            `json:"minSize"`
+	Mails   []largeMailEntry `json:"mails"`
+}
+
+// newLargeMailReport lists all mails in md with a size of at least minSize, largest first.
+func newLargeMailReport(md *sglog.MailData, minSize int64) largeMailReport {
+	lr := largeMailReport{MinSize: minSize, Mails: []largeMailEntry{}}
+	for _, partner := range md.Partner {
+		for _, mail := range partner.Mails {
+			if mail.Size < minSize {
+				continue
+			}
+			lr.Mails = append(lr.Mails, largeMailEntry{Date: mail.Date, Time: mail.Time, Type: mail.GetType(), From: mail.From, To: mail.To, Size: mail.Size, Subject: mail.Subject, MailID: mail.MailID})
+		}
+	}
+	sort.Slice(lr.Mails, func(i, j int) bool {
+		a, b := lr.Mails[i], lr.Mails[j]
+		if a.Size != b.Size {
+			return a.Size > b.Size
+		}
+		if a.Date+a.Time != b.Date+b.Time {
+			return a.Date+a.Time < b.Date+b.Time
+		}
+		return a.MailID < b.MailID
+	})
+	return lr
+}
+
+// ToCSV returns a CSV representation of a largeMailReport object.
+func (lr *largeMailReport) ToCSV(withHeader bool) string {
+	var lines []string
+	if withHeader {
+		lines = append(lines, largeMailReportCSVHeader)
+	}
+	for _, entry := range lr.Mails {
+		lines = append(lines, fmt.Sprintf(largeMailReportCSVFormat, entry.Date, entry.Time, entry.Type, entry.From, entry.To, entry.Size, csvQuote(entry.Subject), entry.MailID))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ToTable returns a human-readable representation of a largeMailReport object.
+func (lr *largeMailReport) ToTable() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Mails of at least %s\n\n", formatBytes(lr.MinSize))
+	var tt textTable
+	tt.SetHeader("date", "time", "type", "from", "to", "size", "subject")
+	tt.AlignRight(5)
+	for _, entry := range lr.Mails {
+		tt.AddRow(entry.Date, entry.Time, entry.Type, entry.From, entry.To, formatBytes(entry.Size), entry.Subject)
+	}
+	sb.WriteString(tt.String())
+	return sb.String()
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"gitlab.com/rbrt-weiler/sophos-sg-smtp-logparser/pkg/sglog"
+)
+
+const (
+	pseudonymKeyEnv    string = "SSSLP_PSEUDONYMIZE_KEY" // Environment variable that may hold the pseudonymization key
+	pseudonymLength    int    = 16                       // Number of hex characters used from the HMAC
+	pseudonymDomainTLD string = "invalid"                // Top level domain used for pseudonymized domains
+	redactedSubject    string = "[redacted]"             // Replacement for redacted subjects
+)
+
+// pseudonymizer replaces personal data in parsed mails with keyed HMAC tokens.
+// Tokens are consistent for the same key, so results of different runs can be correlated.
+type pseudonymizer struct {
+	key         []byte
+	addresses   bool
+	domains     bool
+	subjectMode string
+}
+
+// newPseudonymizer creates a pseudonymizer.
+// The key is read from keyFile or, if keyFile is empty, from the environment. If neither provides a key, a random key is generated.
+// The returned bool is true if a random key was generated.
+func newPseudonymizer(keyFile string, addresses bool, domains bool, subjectMode string) (pseudonymizer, bool, error) {
+	ps := pseudonymizer{addresses: addresses, domains: addresses && domains, subjectMode: subjectMode}
+	randomKey := false
+	if keyFile != "" {
+		content, readErr := os.ReadFile(keyFile)
+		if readErr != nil {
+			return ps, randomKey, fmt.Errorf("Could not read pseudonymization key: %s", readErr)
+		}
+		ps.key = []byte(strings.TrimSpace(string(content)))
+	} else {
+		ps.key = []byte(os.Getenv(pseudonymKeyEnv))
+	}
+	if len(ps.key) == 0 {
+		if keyFile != "" {
+			return ps, randomKey, fmt.Errorf("Pseudonymization key file <%s> is empty", keyFile)
+		}
+		ps.key = make([]byte, 32)
+		if _, randErr := rand.Read(ps.key); randErr != nil {
+			return ps, randomKey, fmt.Errorf("Could not generate pseudonymization key: %s", randErr)
+		}
+		randomKey = true
+	}
+	return ps, randomKey, nil
+}
+
+// token returns the keyed HMAC of value, prefixed by its kind to separate different namespaces.
+func (ps *pseudonymizer) token(kind string, value string) string {
+	mac := hmac.New(sha256.New, ps.key)
+	mac.Write([]byte(kind + ":" + value))
+	return hex.EncodeToString(mac.Sum(nil))[:pseudonymLength]
+}
+
+// User returns the pseudonym of the local part of an e-mail address.
+// The full address is used for computing the pseudonym, so equal local parts in different domains cannot be linked.
+func (ps *pseudonymizer) User(user string, host string) string {
+	return "u-" + ps.token("user", user+"@"+host)
+}
+
+// Host returns the pseudonym of the host part of an e-mail address.
+// Hosts are only pseudonymized if the pseudonymizer was configured to do so.
+func (ps *pseudonymizer) Host(host string) string {
+	if !ps.domains {
+		return host
+	}
+	return fmt.Sprintf("d-%s.%s", ps.token("host", host), pseudonymDomainTLD)
+}
+
+// Subject returns the subject of a mail after applying the configured subject mode.
+func (ps *pseudonymizer) Subject(subject string) string {
+	switch ps.subjectMode {
+	case "redact":
+		return redactedSubject
+	case "hash":
+		return "s-" + ps.token("subject", subject)
+	default:
+		return subject
+	}
+}
+
+// Mail returns a copy of mail with all personal data replaced by pseudonyms.
+// The MailID is generated again, as it would otherwise allow to verify guessed addresses. Addresses as found before normalization and the IP address of the client are removed.
+func (ps *pseudonymizer) Mail(mail sglog.SingleMail) sglog.SingleMail {
+	mail.Subject = ps.Subject(mail.Subject)
+	if !ps.addresses {
+		return mail
+	}
+	if mail.From != sglog.NullSender {
+		mail.UserFrom = ps.User(mail.UserFrom, mail.HostFrom)
+		mail.HostFrom = ps.Host(mail.HostFrom)
+		mail.From = fmt.Sprintf("%s@%s", mail.UserFrom, mail.HostFrom)
+	}
+	mail.UserTo = ps.User(mail.UserTo, mail.HostTo)
+	mail.HostTo = ps.Host(mail.HostTo)
+	mail.To = fmt.Sprintf("%s@%s", mail.UserTo, mail.HostTo)
+	mail.FromRaw, mail.ToRaw = "", ""
+	mail.SrcIP = ""
+	mail.GenerateMailID()
+	return mail
+}
+
+// Data returns a copy of md with all mails pseudonymized.
+// Types and statistics of the mailPartners are retained from the original values.
+func (ps *pseudonymizer) Data(md *sglog.MailData) sglog.MailData {
+	pseudonymized := sglog.MailData{
+		CreateDateTime:     md.CreateDateTime,
+		CreateDateTimeUnix: md.CreateDateTimeUnix,
+		CreateDate:         md.CreateDate,
+		CreateTime:         md.CreateTime,
+	}
+	for _, partner := range md.Partner {
+		for _, mail := range partner.Mails {
+			pseudonymized.Append(ps.Mail(mail))
+		}
+	}
+	return pseudonymized
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"gitlab.com/rbrt-weiler/sophos-sg-smtp-logparser/pkg/sglog"
+)
+
+func TestPseudonymizerMail(t *testing.T) {
+	tests := []struct {
+		name        string
+		ps          pseudonymizer
+		from        string
+		wantFrom    string
+		wantTo      string
+		wantSubject string
+		wantSrcIP   string
+	}{
+		{"subjects only", pseudonymizer{key: []byte("secret"), subjectMode: "redact"}, "someone@example.com", "someone@example.com", "other@else.example.org", redactedSubject, "10.1.2.3"},
+		{"addresses", pseudonymizer{key: []byte("secret"), addresses: true, subjectMode: "keep"}, "someone@example.com", "u-*@example.com", "u-*@else.example.org", "Quarterly report", ""},
+		{"addresses and domains", pseudonymizer{key: []byte("secret"), addresses: true, domains: true, subjectMode: "hash"}, "someone@example.com", "u-*@d-*.invalid", "u-*@d-*.invalid", "s-*", ""},
+		{"null sender", pseudonymizer{key: []byte("secret"), addresses: true, subjectMode: "keep"}, sglog.NullSender, sglog.NullSender, "u-*@else.example.org", "Quarterly report", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mail := testMail(tt.from, "other@else.example.org", "2020-07-18", "16:56:31", "Quarterly report", 10)
+			mail.SetSrcIP("10.1.2.3")
+			mail.FromRaw, mail.ToRaw = "Someone+news@Example.COM", "Other@Else.Example.ORG"
+			got := tt.ps.Mail(mail)
+			if !matchesPseudonym(got.From, tt.wantFrom) || !matchesPseudonym(got.To, tt.wantTo) || !matchesPseudonym(got.Subject, tt.wantSubject) {
+				t.Errorf("Mail() from, to, subject = %q, %q, %q, want %q, %q, %q", got.From, got.To, got.Subject, tt.wantFrom, tt.wantTo, tt.wantSubject)
+			}
+			if got.SrcIP != tt.wantSrcIP {
+				t.Errorf("Mail() srcIP = %q, want %q", got.SrcIP, tt.wantSrcIP)
+			}
+			if tt.ps.addresses && (got.FromRaw != "" || got.ToRaw != "") {
+				t.Errorf("Mail() fromRaw, toRaw = %q, %q, want empty", got.FromRaw, got.ToRaw)
+			}
+			if tt.ps.addresses && got.MailID == mail.MailID {
+				t.Error("Mail() kept the mailID of the original mail")
+			}
+		})
+	}
+}
+
+func TestPseudonymizerTokens(t *testing.T) {
+	ps := pseudonymizer{key: []byte("secret"), addresses: true}
+	other := pseudonymizer{key: []byte("other secret"), addresses: true}
+	if ps.User("someone", "example.com") != ps.User("someone", "example.com") {
+		t.Error("User() differs for the same address")
+	}
+	if ps.User("someone", "example.com") == ps.User("someone", "else.example.org") {
+		t.Error("User() is equal for the same local part in different domains")
+	}
+	if ps.User("someone", "example.com") == other.User("someone", "example.com") {
+		t.Error("User() is equal for different keys")
+	}
+	if got := ps.Host("example.com"); got != "example.com" {
+		t.Errorf("Host() = %q without pseudonymized domains", got)
+	}
+}
+
+// matchesPseudonym reports whether value equals pattern, where every * in pattern stands for a token of pseudonymLength hex characters.
+func matchesPseudonym(value string, pattern string) bool {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		if !strings.HasPrefix(value, part) {
+			return false
+		}
+		value = value[len(part):]
+		if i < len(parts)-1 {
+			if len(value) < pseudonymLength || strings.Trim(value[:pseudonymLength], "0123456789abcdef") != "" {
+				return false
+			}
+			value = value[pseudonymLength:]
+		}
+	}
+	return value == ""
+}
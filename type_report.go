@@ -0,0 +1,7 @@
+package main
+
+// report is implemented by all reports that can be rendered as CSV, JSON or a human-readable table.
+type report interface {
+	ToCSV(withHeader bool) string
+	ToTable() string
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"gitlab.com/rbrt-weiler/sophos-sg-smtp-logparser/pkg/sglog"
+)
+
+var (
+	// Format strings for CSV output
+	responseReportCSVHeader = "scope,type,key,responder,responses,median,p75,p90,p95,max"
+	responseReportCSVFormat = "%s,%s,%s,%s,%d,%d,%d,%d,%d,%d"
+)
+
+// Stores the response time statistics of a partner or an internal domain in seconds.
+type responseEntry struct {
+	Scope     string `json:"scope"`
+	Type      string `json:"type"`
+	Key       string `json:"key"`
+	Responder string `json:"responder"`
+	Responses int64  `json:"responses"`
+	Median    int64  `json:"median"`
+	P75       int64  `json:"p75"`
+	P90       int64  `json:"p90"`
+	P95       int64  `json:"p95"`
+	Max       int64  `json:"max"`
+}
+
+// Stores the response times of all two-way partners and internal domains.
+type responseReport struct {
+	Domains  []responseEntry `json:"domains"`
+	Partners []responseEntry `json:"partners"`
+}
+
+// newResponseReport computes response times for all two-way mailPartners in md.
+// A response is the first mail sent by one partner after one or more mails of the other partner; it is measured from the first of these unanswered mails.
+func newResponseReport(md *sglog.MailData) responseReport {
+	rr := responseReport{Domains: []responseEntry{}, Partners: []responseEntry{}}
+	domains := make(map[string][]int64)
+	for _, partner := range md.Partner {
+		if !partner.IsTwoWay {
+			continue
+		}
+		mails := make([]sglog.SingleMail, len(partner.Mails))
+		copy(mails, partner.Mails)
+		sortMails(mails)
+		responses := make(map[string][]int64)
+		var waiting sglog.SingleMail
+		var waitingSince time.Time
+		for i, mail := range mails {
+			sent, sentErr := time.Parse("2006-01-02 15:04:05", mail.Date+" "+mail.Time)
+			if sentErr != nil {
+				continue
+			}
+			if i > 0 && waiting.From != "" && mail.From != waiting.From {
+				seconds := int64(sent.Sub(waitingSince).Seconds())
+				responses[mail.From] = append(responses[mail.From], seconds)
+				if mail.TypeFrom == "internal" {
+					domain := "domain " + displayHost(mail.HostFrom, mail.From)
+					domains[domain] = append(domains[domain], seconds)
+				}
+				if waiting.TypeFrom == "internal" {
+					domain := "partners " + displayHost(waiting.HostFrom, waiting.From)
+					domains[domain] = append(domains[domain], seconds)
+				}
+			}
+			if mail.From != waiting.From {
+				waiting, waitingSince = mail, sent
+			}
+		}
+		for _, responder := range []string{partner.PartnerB, partner.PartnerA} {
+			if len(responses[responder]) > 0 {
+				entry := newResponseEntry(responses[responder])
+				entry.Scope, entry.Type, entry.Key, entry.Responder = "partner", partner.Type, partner.PartnerA+" <-> "+partner.PartnerB, responder
+				rr.Partners = append(rr.Partners, entry)
+			}
+		}
+	}
+	for key, seconds := range domains {
+		responder, domain, _ := strings.Cut(key, " ")
+		entry := newResponseEntry(seconds)
+		entry.Scope, entry.Type, entry.Key, entry.Responder = "domain", "internal", domain, responder
+		rr.Domains = append(rr.Domains, entry)
+	}
+
+	sortEntries := func(entries []responseEntry) {
+		sort.Slice(entries, func(i, j int) bool {
+			if entries[i].Key != entries[j].Key {
+				return entries[i].Key < entries[j].Key
+			}
+			return entries[i].Responder < entries[j].Responder
+		})
+	}
+	sortEntries(rr.Domains)
+	sortEntries(rr.Partners)
+	return rr
+}
+
+// newResponseEntry creates a responseEntry with the statistics of all response times in seconds.
+func newResponseEntry(seconds []int64) responseEntry {
+	sort.Slice(seconds, func(i, j int) bool {
+		return seconds[i] < seconds[j]
+	})
+	return responseEntry{
+		Responses: int64(len(seconds)),
+		Median:    percentile(seconds, 50),
+		P75:       percentile(seconds, 75),
+		P90:       percentile(seconds, 90),
+		P95:       percentile(seconds, 95),
+		Max:       seconds[len(seconds)-1],
+	}
+}
+
+// percentile returns the p-th percentile of the sorted values using the nearest-rank method.
+func percentile(sorted []int64, p float64) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// ToCSV returns a CSV representation of a responseReport object.
+func (rr *responseReport) ToCSV(withHeader bool) string {
+	var lines []string
+	if withHeader {
+		lines = append(lines, responseReportCSVHeader)
+	}
+	for _, entries := range [][]responseEntry{rr.Domains, rr.Partners} {
+		for _, entry := range entries {
+			lines = append(lines, fmt.Sprintf(responseReportCSVFormat, entry.Scope, entry.Type, entry.Key, entry.Responder, entry.Responses, entry.Median, entry.P75, entry.P90, entry.P95, entry.Max))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ToTable returns a human-readable representation of a responseReport object.
+func (rr *responseReport) ToTable() string {
+	var sb strings.Builder
+	sections := []struct {
+		title   string
+		header  string
+		entries []responseEntry
+	}{
+		{"Response times by internal domain", "domain", rr.Domains},
+		{"Response times by partner", "partner", rr.Partners},
+	}
+	for _, section := range sections {
+		sb.WriteString(section.title)
+		sb.WriteString("\n\n")
+		var tt textTable
+		tt.SetHeader(section.header, "responder", "responses", "median", "p75", "p90", "p95", "max")
+		tt.AlignRight(2, 3, 4, 5, 6, 7)
+		for _, entry := range section.entries {
+			tt.AddRow(entry.Key, entry.Responder, formatCount(entry.Responses), formatDuration(entry.Median), formatDuration(entry.P75), formatDuration(entry.P90), formatDuration(entry.P95), formatDuration(entry.Max))
+		}
+		sb.WriteString(tt.String())
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
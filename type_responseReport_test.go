@@ -0,0 +1,78 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		name   string
+		sorted []int64
+		p      float64
+		want   int64
+	}{
+		{"no samples", nil, 50, 0},
+		{"one sample median", []int64{30}, 50, 30},
+		{"one sample p95", []int64{30}, 95, 30},
+		{"two samples median", []int64{30, 90}, 50, 30},
+		{"two samples p75", []int64{30, 90}, 75, 90},
+		{"two samples p95", []int64{30, 90}, 95, 90},
+		{"ten samples p90", []int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 90, 9},
+		{"ten samples p95", []int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 95, 10},
+		{"zeroth percentile", []int64{1, 2}, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := percentile(tt.sorted, tt.p); got != tt.want {
+				t.Errorf("percentile(%v, %v) = %d, want %d", tt.sorted, tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewResponseEntry(t *testing.T) {
+	got := newResponseEntry([]int64{600, 60, 3600})
+	want := responseEntry{Responses: 3, Median: 600, P75: 3600, P90: 3600, P95: 3600, Max: 3600}
+	if got != want {
+		t.Errorf("newResponseEntry() = %+v, want %+v", got, want)
+	}
+}
+
+func TestNewResponseReport(t *testing.T) {
+	md := testData(
+		testMail("a@example.com", "b@else.example.org", "2020-07-18", "10:00:00", "One", 10),
+		testMail("a@example.com", "b@else.example.org", "2020-07-18", "10:05:00", "Two", 10),
+		testMail("b@else.example.org", "a@example.com", "2020-07-18", "10:20:00", "Re: One", 10),
+		testMail("a@example.com", "b@else.example.org", "2020-07-18", "10:21:00", "Re: Re: One", 10),
+		testMail("a@example.com", "c@else.example.org", "2020-07-18", "10:00:00", "One-way", 10),
+	)
+	rr := newResponseReport(&md)
+	wantPartners := []responseEntry{
+		{Scope: "partner", Type: "e2i", Key: "b@else.example.org <-> a@example.com", Responder: "a@example.com", Responses: 1, Median: 60, P75: 60, P90: 60, P95: 60, Max: 60},
+		{Scope: "partner", Type: "e2i", Key: "b@else.example.org <-> a@example.com", Responder: "b@else.example.org", Responses: 1, Median: 1200, P75: 1200, P90: 1200, P95: 1200, Max: 1200},
+	}
+	if !reflect.DeepEqual(rr.Partners, wantPartners) {
+		t.Errorf("newResponseReport() partners = %+v, want %+v", rr.Partners, wantPartners)
+	}
+	wantDomains := []responseEntry{
+		{Scope: "domain", Type: "internal", Key: "example.com", Responder: "domain", Responses: 1, Median: 60, P75: 60, P90: 60, P95: 60, Max: 60},
+		{Scope: "domain", Type: "internal", Key: "example.com", Responder: "partners", Responses: 1, Median: 1200, P75: 1200, P90: 1200, P95: 1200, Max: 1200},
+	}
+	if !reflect.DeepEqual(rr.Domains, wantDomains) {
+		t.Errorf("newResponseReport() domains = %+v, want %+v", rr.Domains, wantDomains)
+	}
+}
+
+func TestNewResponseReportSameTimestamp(t *testing.T) {
+	question := testMail("a@example.com", "b@else.example.org", "2020-07-18", "10:00:00", "One", 10)
+	answer := testMail("b@else.example.org", "a@example.com", "2020-07-18", "10:00:00", "Re: One", 10)
+	followUp := testMail("a@example.com", "b@else.example.org", "2020-07-18", "10:05:00", "Two", 10)
+
+	forward := testData(question, answer, followUp)
+	backward := testData(answer, question, followUp)
+	got, reversed := newResponseReport(&forward), newResponseReport(&backward)
+	if !reflect.DeepEqual(got, reversed) {
+		t.Errorf("newResponseReport() depends on the order of mails: %+v, %+v", got, reversed)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gitlab.com/rbrt-weiler/sophos-sg-smtp-logparser/pkg/sglog"
+)
+
+const (
+	siemVendor     string = "Sophos"               // Device vendor in CEF and LEEF headers
+	siemProduct    string = "SG SMTP"              // Device product in CEF and LEEF headers
+	siemSeverity   int    = 3                      // CEF severity of every mail, in the range 0 to 10
+	siemTimeLayout string = "Jan 02 2006 15:04:05" // Layout of event timestamps, MMM dd yyyy HH:mm:ss
+)
+
+var (
+	// Escaping of CEF header fields and extension values
+	cefHeaderEscaper    = strings.NewReplacer("\\", "\\\\", "|", "\\|", "\r", " ", "\n", " ")
+	cefExtensionEscaper = strings.NewReplacer("\\", "\\\\", "=", "\\=", "\r", "\\r", "\n", "\\n")
+	// LEEF has no escaping, so the attribute delimiter and line breaks are replaced
+	leefValueEscaper = strings.NewReplacer("\t", " ", "\r", " ", "\n", " ")
+)
+
+// Stores all mails as CEF or LEEF events, one event per line.
+type siemExport struct {
+	Events []string
+}
+
+// newSIEMExport creates a siemExport from all mails in md, ordered by date and time.
+// format selects between "cef" for ArcSight and "leef" for QRadar.
+func newSIEMExport(md *sglog.MailData, format string) siemExport {
+	var se siemExport
+	for _, mail := range sortedMails(md) {
+		if format == "leef" {
+			se.Events = append(se.Events, leefEvent(&mail))
+		} else {
+			se.Events = append(se.Events, cefEvent(&mail))
+		}
+	}
+	return se
+}
+
+// cefEvent returns mail as an event in ArcSight Common Event Format.
+func cefEvent(mail *sglog.SingleMail) string {
+	header := []string{"CEF:0", siemVendor, siemProduct, toolVersion, mail.GetType(), siemEventName(mail), fmt.Sprint(siemSeverity)}
+	for i := 1; i < len(header); i++ {
+		header[i] = cefHeaderEscaper.Replace(header[i])
+	}
+	var extension []string
+	for _, field := range siemFields(mail) {
+		extension = append(extension, field[0]+"="+cefExtensionEscaper.Replace(field[1]))
+	}
+	return strings.Join(header, "|") + "|" + strings.Join(extension, " ")
+}
+
+// leefEvent returns mail as an event in QRadar Log Event Extended Format 1.0.
+func leefEvent(mail *sglog.SingleMail) string {
+	header := []string{"LEEF:1.0", siemVendor, siemProduct, toolVersion, mail.GetType()}
+	attributes := []string{"devTimeFormat=MMM dd yyyy HH:mm:ss", "cat=" + siemEventName(mail)}
+	for _, field := range siemFields(mail) {
+		name := field[0]
+		if name == "rt" {
+			name = "devTime"
+		}
+		attributes = append(attributes, name+"="+leefValueEscaper.Replace(field[1]))
+	}
+	return strings.Join(header, "|") + "|" + strings.Join(attributes, "\t")
+}
+
+// siemEventName returns a description of mail like "Mail from internal to external".
+func siemEventName(mail *sglog.SingleMail) string {
+	return fmt.Sprintf("Mail from %s to %s", mail.TypeFrom, mail.TypeTo)
+}
+
+// siemFields returns the key-value pairs of mail that are shared by CEF and LEEF events, using CEF key names.
+// Empty values are omitted.
+func siemFields(mail *sglog.SingleMail) [][2]string {
+	timestamp := mail.Date + " " + mail.Time
+	if t, parseErr := time.Parse("2006-01-02 15:04:05", timestamp); parseErr == nil {
+		timestamp = t.Format(siemTimeLayout)
+	}
+	fields := [][2]string{
+		{"rt", timestamp},
+		{"src", mail.SrcIP},
+		{"suser", mail.From},
+		{"duser", mail.To},
+		{"fsize", fmt.Sprint(mail.Size)},
+		{"msg", mail.Subject},
+		{"externalId", mail.QueueID},
+		{"cs1Label", "mailID"},
+		{"cs1", mail.MailID},
+	}
+	var nonEmpty [][2]string
+	for _, field := range fields {
+		if field[1] != "" {
+			nonEmpty = append(nonEmpty, field)
+		}
+	}
+	return nonEmpty
+}
+
+// ToText returns all events, one event per line.
+func (se *siemExport) ToText() string {
+	return strings.Join(se.Events, "\n")
+}
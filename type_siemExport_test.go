@@ -0,0 +1,56 @@
+package main
+
+import "testing"
+
+func TestCEFEscapers(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         string
+		wantHeader    string
+		wantExtension string
+	}{
+		{"plain", "Some e-mail conversation", "Some e-mail conversation", "Some e-mail conversation"},
+		{"pipe", "a|b", `a\|b`, "a|b"},
+		{"equals sign", "a=b", "a=b", `a\=b`},
+		{"backslash", `a\b`, `a\\b`, `a\\b`},
+		{"line breaks", "a\r\nb", "a  b", `a\r\nb`},
+		{"escaped pipe", `a\|b`, `a\\\|b`, `a\\|b`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cefHeaderEscaper.Replace(tt.value); got != tt.wantHeader {
+				t.Errorf("cefHeaderEscaper.Replace(%q) = %q, want %q", tt.value, got, tt.wantHeader)
+			}
+			if got := cefExtensionEscaper.Replace(tt.value); got != tt.wantExtension {
+				t.Errorf("cefExtensionEscaper.Replace(%q) = %q, want %q", tt.value, got, tt.wantExtension)
+			}
+		})
+	}
+}
+
+func TestSIEMEvents(t *testing.T) {
+	mail := testMail("someone@example.com", "other@else.example.org", "2020-07-18", "16:56:31", "Re: a|b=c\td\\e\nf", 587538)
+	mail.SetSrcIP("10.1.2.3")
+	mail.SetQueueID("1abCdE-0a6b1f-A4")
+	mail.GenerateMailID()
+
+	tests := []struct {
+		name  string
+		event string
+		want  string
+	}{
+		{"CEF", cefEvent(&mail), "CEF:0|Sophos|SG SMTP|" + toolVersion + "|i2e|Mail from internal to external|3|" +
+			"rt=Jul 18 2020 16:56:31 src=10.1.2.3 suser=someone@example.com duser=other@else.example.org fsize=587538 " +
+			"msg=Re: a|b\\=c\td\\\\e\\nf externalId=1abCdE-0a6b1f-A4 cs1Label=mailID cs1=" + mail.MailID},
+		{"LEEF", leefEvent(&mail), "LEEF:1.0|Sophos|SG SMTP|" + toolVersion + "|i2e|devTimeFormat=MMM dd yyyy HH:mm:ss\tcat=Mail from internal to external\t" +
+			"devTime=Jul 18 2020 16:56:31\tsrc=10.1.2.3\tsuser=someone@example.com\tduser=other@else.example.org\tfsize=587538\t" +
+			"msg=Re: a|b=c d\\e f\texternalId=1abCdE-0a6b1f-A4\tcs1Label=mailID\tcs1=" + mail.MailID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.event != tt.want {
+				t.Errorf("event = %q, want %q", tt.event, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gitlab.com/rbrt-weiler/sophos-sg-smtp-logparser/pkg/sglog"
+)
+
+const (
+	sizeHistogramBase    int64 = 1024 // Upper bound of the first histogram bucket in bytes
+	sizeHistogramFactor  int64 = 4    // Factor between the upper bounds of consecutive histogram buckets
+	sizeHistogramBuckets int   = 10   // Number of histogram buckets, the last one being unbounded
+	sizeHistogramWidth   int   = 40   // Maximum width of histogram bars in table output
+)
+
+var (
+	// Format strings for CSV output
+	sizeReportCSVHeader = "scope,type,key,mails,min,max,mean,median,p90,p95,p99"
+	sizeReportCSVFormat = "%s,%s,%s,%d,%d,%d,%d,%d,%d,%d,%d"
+)
+
+// Stores the number of mails in a single histogram bucket; an upper bound of 0 means unbounded.
+type sizeBucket struct {
+	LowerBound int64 `json:"lowerBound"`
+	UpperBound int64 `json:"upperBound"`
+	Mails      int64 `json:"mails"`
+}
+
+// Stores the size distribution of a set of mails in bytes.
+type sizeStats struct {
+	Type      string       `json:"type,omitempty"`
+	Key       string       `json:"key,omitempty"`
+	Mails     int64        `json:"mails"`
+	Min       int64        `json:"min"`
+	Max       int64        `json:"max"`
+	Mean      int64        `json:"mean"`
+	Median    int64        `json:"median"`
+	P90       int64        `json:"p90"`
+	P95       int64        `json:"p95"`
+	P99       int64        `json:"p99"`
+	Histogram []sizeBucket `json:"histogram"`
+}
+
+// Stores the size distribution of all mails and of every partner.
+type sizeReport struct {
+	Global   sizeStats   `json:"global"`
+	Partners []sizeStats `json:"partners"`
+}
+
+// newSizeReport creates a sizeReport from all mails stored in md.
+func newSizeReport(md *sglog.MailData) sizeReport {
+	sr := sizeReport{Partners: []sizeStats{}}
+	var all []int64
+	for partnerKey, partner := range md.Partner {
+		sizes := make([]int64, 0, len(partner.Mails))
+		for _, mail := range partner.Mails {
+			sizes = append(sizes, mail.Size)
+		}
+		all = append(all, sizes...)
+		stats := newSizeStats(sizes)
+		stats.Type, stats.Key = partner.Type, partnerKey
+		sr.Partners = append(sr.Partners, stats)
+	}
+	sr.Global = newSizeStats(all)
+	sort.Slice(sr.Partners, func(i, j int) bool {
+		a, b := sr.Partners[i], sr.Partners[j]
+		if a.Max != b.Max {
+			return a.Max > b.Max
+		}
+		return a.Key < b.Key
+	})
+	return sr
+}
+
+// newSizeStats computes the size distribution of sizes.
+func newSizeStats(sizes []int64) sizeStats {
+	stats := sizeStats{Histogram: make([]sizeBucket, sizeHistogramBuckets)}
+	lower, upper := int64(0), sizeHistogramBase
+	for i := range stats.Histogram {
+		stats.Histogram[i].LowerBound = lower
+		if i < sizeHistogramBuckets-1 {
+			stats.Histogram[i].UpperBound = upper
+		}
+		lower, upper = upper, upper*sizeHistogramFactor
+	}
+	if len(sizes) == 0 {
+		return stats
+	}
+	sort.Slice(sizes, func(i, j int) bool {
+		return sizes[i] < sizes[j]
+	})
+	var sum int64
+	for _, size := range sizes {
+		sum = sum + size
+		bucket := 0
+		for bucket < sizeHistogramBuckets-1 && size >= stats.Histogram[bucket].UpperBound {
+			bucket++
+		}
+		stats.Histogram[bucket].Mails++
+	}
+	stats.Mails = int64(len(sizes))
+	stats.Min, stats.Max = sizes[0], sizes[len(sizes)-1]
+	stats.Mean = sum / stats.Mails
+	stats.Median = percentile(sizes, 50)
+	stats.P90 = percentile(sizes, 90)
+	stats.P95 = percentile(sizes, 95)
+	stats.P99 = percentile(sizes, 99)
+	return stats
+}
+
+// ToCSV returns a CSV representation of a sizeReport object.
+// Histograms are omitted; they are available in JSON and table output.
+func (sr *sizeReport) ToCSV(withHeader bool) string {
+	var lines []string
+	if withHeader {
+		lines = append(lines, sizeReportCSVHeader)
+	}
+	line := func(scope string, stats sizeStats) string {
+		return fmt.Sprintf(sizeReportCSVFormat, scope, stats.Type, stats.Key, stats.Mails, stats.Min, stats.Max, stats.Mean, stats.Median, stats.P90, stats.P95, stats.P99)
+	}
+	lines = append(lines, line("global", sr.Global))
+	for _, stats := range sr.Partners {
+		lines = append(lines, line("partner", stats))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ToTable returns a human-readable representation of a sizeReport object.
+func (sr *sizeReport) ToTable() string {
+	var sb strings.Builder
+	sb.WriteString("Size distribution of all mails\n\n")
+	var global textTable
+	global.SetHeader("mails", "min", "max", "mean", "median", "p90", "p95", "p99")
+	global.AlignRight(0, 1, 2, 3, 4, 5, 6, 7)
+	global.AddRow(formatCount(sr.Global.Mails), formatBytes(sr.Global.Min), formatBytes(sr.Global.Max), formatBytes(sr.Global.Mean), formatBytes(sr.Global.Median), formatBytes(sr.Global.P90), formatBytes(sr.Global.P95), formatBytes(sr.Global.P99))
+	sb.WriteString(global.String())
+	sb.WriteString("\n")
+
+	sb.WriteString("Size histogram of all mails\n\n")
+	var peak int64
+	for _, bucket := range sr.Global.Histogram {
+		if bucket.Mails > peak {
+			peak = bucket.Mails
+		}
+	}
+	var histogram textTable
+	histogram.SetHeader("size", "mails", "")
+	histogram.AlignRight(1)
+	for _, bucket := range sr.Global.Histogram {
+		label := fmt.Sprintf("%s - %s", formatBytes(bucket.LowerBound), formatBytes(bucket.UpperBound))
+		if bucket.UpperBound == 0 {
+			label = fmt.Sprintf("%s and more", formatBytes(bucket.LowerBound))
+		}
+		bar := ""
+		if peak > 0 {
+			bar = strings.Repeat("#", int(bucket.Mails*int64(sizeHistogramWidth)/peak))
+		}
+		histogram.AddRow(label, formatCount(bucket.Mails), bar)
+	}
+	sb.WriteString(histogram.String())
+	sb.WriteString("\n")
+
+	sb.WriteString("Size distribution by partner\n\n")
+	var partners textTable
+	partners.SetHeader("type", "partner", "mails", "min", "max", "mean", "median", "p90", "p95", "p99")
+	partners.AlignRight(2, 3, 4, 5, 6, 7, 8, 9)
+	for _, stats := range sr.Partners {
+		partners.AddRow(stats.Type, stats.Key, formatCount(stats.Mails), formatBytes(stats.Min), formatBytes(stats.Max), formatBytes(stats.Mean), formatBytes(stats.Median), formatBytes(stats.P90), formatBytes(stats.P95), formatBytes(stats.P99))
+	}
+	sb.WriteString(partners.String())
+	return sb.String()
+}
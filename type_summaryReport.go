@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gitlab.com/rbrt-weiler/sophos-sg-smtp-logparser/pkg/sglog"
+)
+
+const (
+	summaryTopPartners  int = 10 // Number of partners listed in a summaryReport
+	summaryBusiestHours int = 5  // Number of hours listed in a summaryReport
+)
+
+// Stores mail volume for a single communication type or hour of day.
+type summaryTotal struct {
+	Key      string
+	Partners int64
+	Mails    int64
+	Size     int64
+}
+
+// Stores an overview of the mail traffic that is intended for human readers.
+type summaryReport struct {
+	FirstSeen     string
+	LastSeen      string
+	Types         []summaryTotal
+	Partners      int64
+	TopPartners   []topEntry
+	TopBounced    []topEntry
+	PartnerTypes  map[string]string
+	BusiestHours  []summaryTotal
+	HasStats      bool
+	Files         int64
+	LinesRead     int64
+	RelevantLines int64
+	ParsedMails   int64
+	SkippedLines  int64
+	SkipReasons   []string
+	SkipCounts    []int64
+}
+
+// newSummaryReport creates a summaryReport from all mails stored in md and the statistics in ps.
+// If ps is nil, as for merged data, the parse statistics are omitted.
+func newSummaryReport(md *sglog.MailData, ps *sglog.ParseStats) summaryReport {
+	sr := summaryReport{PartnerTypes: make(map[string]string)}
+	types := make(map[string]*summaryTotal)
+	for _, t := range []string{"i2i", "i2e", "e2i", "e2e"} {
+		types[t] = &summaryTotal{Key: t}
+	}
+	bounced := make(map[string]*topEntry)
+	hours := make([]summaryTotal, 24)
+	for i := range hours {
+		hours[i].Key = fmt.Sprintf("%02d:00-%02d:59", i, i)
+	}
+
+	for partnerKey, partner := range md.Partner {
+		sr.PartnerTypes[partnerKey] = partner.Type
+		sr.Partners++
+		partnerMailTypes := make(map[string]bool)
+		for _, mail := range partner.Mails {
+			mailType := mail.GetType()
+			if _, ok := types[mailType]; !ok {
+				types[mailType] = &summaryTotal{Key: mailType}
+			}
+			if !partnerMailTypes[mailType] {
+				partnerMailTypes[mailType] = true
+				types[mailType].Partners++
+			}
+			types[mailType].Mails++
+			types[mailType].Size = types[mailType].Size + mail.Size
+			if hour, hourErr := strconv.Atoi(strings.SplitN(mail.Time, ":", 2)[0]); hourErr == nil && hour >= 0 && hour < 24 {
+				hours[hour].Mails++
+				hours[hour].Size = hours[hour].Size + mail.Size
+			}
+			if mail.From == sglog.NullSender {
+				if _, ok := bounced[mail.To]; !ok {
+					bounced[mail.To] = &topEntry{Key: mail.To}
+				}
+				bounced[mail.To].Mails++
+				bounced[mail.To].Size = bounced[mail.To].Size + mail.Size
+			}
+			timestamp := fmt.Sprintf("%s %s", mail.Date, mail.Time)
+			if sr.FirstSeen == "" || timestamp < sr.FirstSeen {
+				sr.FirstSeen = timestamp
+			}
+			if timestamp > sr.LastSeen {
+				sr.LastSeen = timestamp
+			}
+		}
+	}
+
+	for _, total := range types {
+		sr.Types = append(sr.Types, *total)
+	}
+	sort.Slice(sr.Types, func(i, j int) bool {
+		return sr.Types[i].Key < sr.Types[j].Key
+	})
+
+	sort.SliceStable(hours, func(i, j int) bool {
+		return hours[i].Mails > hours[j].Mails
+	})
+	for _, hour := range hours {
+		if hour.Mails == 0 || len(sr.BusiestHours) == summaryBusiestHours {
+			break
+		}
+		sr.BusiestHours = append(sr.BusiestHours, hour)
+	}
+
+	tr := newTopReport(md, summaryTopPartners, "count", "")
+	sr.TopPartners = tr.Partners
+	sr.TopBounced = rankTopEntries(bounced, summaryTopPartners, "count")
+
+	if ps == nil {
+		return sr
+	}
+	sr.HasStats = true
+	sr.Files = ps.Files()
+	sr.LinesRead = ps.LinesRead()
+	sr.RelevantLines = ps.RelevantLines()
+	sr.ParsedMails = ps.ParsedMails()
+	sr.SkippedLines = ps.SkippedLines()
+	sr.SkipReasons, sr.SkipCounts = ps.SkipReasons()
+	return sr
+}
+
+// ToTable returns a human-readable representation of a summaryReport object.
+// Section titles are highlighted with ANSI escape sequences if colour is true.
+func (sr *summaryReport) ToTable(colour bool) string {
+	var sb strings.Builder
+	title := func(text string) {
+		if colour {
+			text = fmt.Sprintf("\033[1m%s\033[0m", text)
+		}
+		sb.WriteString(text)
+		sb.WriteString("\n\n")
+	}
+
+	title(fmt.Sprintf("Mail traffic from %s to %s", sr.FirstSeen, sr.LastSeen))
+
+	title("Totals by type")
+	var types textTable
+	types.SetHeader("type", "partners", "mails", "bytes")
+	types.AlignRight(1, 2, 3)
+	var totalMails, totalSize int64
+	for _, total := range sr.Types {
+		types.AddRow(total.Key, formatCount(total.Partners), formatCount(total.Mails), formatBytes(total.Size))
+		totalMails = totalMails + total.Mails
+		totalSize = totalSize + total.Size
+	}
+	types.AddRow("all", formatCount(sr.Partners), formatCount(totalMails), formatBytes(totalSize))
+	sb.WriteString(types.String())
+	sb.WriteString("\n")
+
+	title("Top partners")
+	var partners textTable
+	partners.SetHeader("#", "type", "partner", "mails", "bytes")
+	partners.AlignRight(0, 3, 4)
+	for _, entry := range sr.TopPartners {
+		partners.AddRow(strconv.Itoa(entry.Rank), sr.PartnerTypes[entry.Key], strings.Replace(entry.Key, " ", " <-> ", 1), formatCount(entry.Mails), formatBytes(entry.Size))
+	}
+	sb.WriteString(partners.String())
+	sb.WriteString("\n")
+
+	if len(sr.TopBounced) > 0 {
+		title("Top bounce recipients")
+		var bounced textTable
+		bounced.SetHeader("#", "recipient", "bounces", "bytes")
+		bounced.AlignRight(0, 2, 3)
+		for _, entry := range sr.TopBounced {
+			bounced.AddRow(strconv.Itoa(entry.Rank), entry.Key, formatCount(entry.Mails), formatBytes(entry.Size))
+		}
+		sb.WriteString(bounced.String())
+		sb.WriteString("\n")
+	}
+
+	title("Busiest hours")
+	var hours textTable
+	hours.SetHeader("hour", "mails", "bytes")
+	hours.AlignRight(1, 2)
+	for _, hour := range sr.BusiestHours {
+		hours.AddRow(hour.Key, formatCount(hour.Mails), formatBytes(hour.Size))
+	}
+	sb.WriteString(hours.String())
+	sb.WriteString("\n")
+
+	if !sr.HasStats {
+		return sb.String()
+	}
+
+	title("Parse statistics")
+	var parsing textTable
+	parsing.AlignRight(1)
+	parsing.AddRow("files", formatCount(sr.Files))
+	parsing.AddRow("lines read", formatCount(sr.LinesRead))
+	parsing.AddRow("relevant lines", formatCount(sr.RelevantLines))
+	parsing.AddRow("parsed mails", formatCount(sr.ParsedMails))
+	parsing.AddRow("skipped lines", formatCount(sr.SkippedLines))
+	for i, reason := range sr.SkipReasons {
+		parsing.AddRow("  "+reason, formatCount(sr.SkipCounts[i]))
+	}
+	sb.WriteString(parsing.String())
+
+	return sb.String()
+}
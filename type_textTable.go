@@ -0,0 +1,90 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// textTable renders rows of strings as an aligned plain text table.
+type textTable struct {
+	header     []string
+	rows       [][]string
+	alignRight map[int]bool
+}
+
+// SetHeader sets the column titles of a textTable.
+func (tt *textTable) SetHeader(columns ...string) {
+	tt.header = columns
+}
+
+// AlignRight marks the given columns (counted from 0) as right-aligned.
+func (tt *textTable) AlignRight(columns ...int) {
+	if tt.alignRight == nil {
+		tt.alignRight = make(map[int]bool)
+	}
+	for _, column := range columns {
+		tt.alignRight[column] = true
+	}
+}
+
+// AddRow appends a new row to a textTable.
+func (tt *textTable) AddRow(columns ...string) {
+	tt.rows = append(tt.rows, columns)
+}
+
+// Len returns the number of rows (excluding the header) in a textTable.
+func (tt *textTable) Len() int {
+	return len(tt.rows)
+}
+
+// String returns the aligned representation of a textTable.
+// Column widths are computed from the widest cell of each column.
+func (tt *textTable) String() string {
+	var widths []int
+	measure := func(row []string) {
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if w := utf8.RuneCountInString(cell); w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+	measure(tt.header)
+	for _, row := range tt.rows {
+		measure(row)
+	}
+
+	var sb strings.Builder
+	render := func(row []string) {
+		for i, cell := range row {
+			padding := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+			if i > 0 {
+				sb.WriteString("  ")
+			}
+			if tt.alignRight[i] {
+				sb.WriteString(padding)
+				sb.WriteString(cell)
+			} else {
+				sb.WriteString(cell)
+				if i < len(row)-1 {
+					sb.WriteString(padding)
+				}
+			}
+		}
+		sb.WriteString("\n")
+	}
+	if len(tt.header) > 0 {
+		render(tt.header)
+		var rule []string
+		for i := range tt.header {
+			rule = append(rule, strings.Repeat("-", widths[i]))
+		}
+		render(rule)
+	}
+	for _, row := range tt.rows {
+		render(row)
+	}
+	return sb.String()
+}
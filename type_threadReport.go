@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.com/rbrt-weiler/sophos-sg-smtp-logparser/pkg/sglog"
+)
+
+var (
+	// Format strings for CSV output
+	threadReportCSVHeader = "type,partnerA,partnerB,subject,mails,participants,firstSeen,lastSeen,responded,responseSeconds"
+	threadReportCSVFormat = "%s,%s,%s,%s,%d,%s,%s,%s,%t,%d"
+)
+
+// Stores a single conversation between two communication partners.
+type threadEntry struct {
+	Type            string   `json:"type"`
+	PartnerA        string   `json:"partnerA"`
+	PartnerB        string   `json:"partnerB"`
+	Subject         string   `json:"subject"`
+	Mails           int64    `json:"mails"`
+	Size            int64    `json:"size"`
+	Participants    []string `json:"participants"`
+	FirstSeen       string   `json:"firstSeen"`
+	LastSeen        string   `json:"lastSeen"`
+	Responded       bool     `json:"responded"`
+	ResponseSeconds int64    `json:"responseSeconds"`
+}
+
+// Stores all conversations found in a MailData object.
+type threadReport struct {
+	Threads []threadEntry `json:"threads"`
+}
+
+// newThreadReport groups the mails of every mailPartner in md into conversations by their normalized subject.
+// The response time of a conversation is the time between its first mail and the first mail sent in the opposite direction.
+func newThreadReport(md *sglog.MailData) threadReport {
+	tr := threadReport{Threads: []threadEntry{}}
+	for _, partner := range md.Partner {
+		threads := make(map[string][]sglog.SingleMail)
+		for _, mail := range partner.Mails {
+			key := strings.ToLower(sglog.NormalizeSubject(mail.Subject))
+			threads[key] = append(threads[key], mail)
+		}
+		for _, mails := range threads {
+			tr.Threads = append(tr.Threads, newThreadEntry(&partner, mails))
+		}
+	}
+	sort.Slice(tr.Threads, func(i, j int) bool {
+		a, b := tr.Threads[i], tr.Threads[j]
+		if a.PartnerA+" "+a.PartnerB != b.PartnerA+" "+b.PartnerB {
+			return a.PartnerA+" "+a.PartnerB < b.PartnerA+" "+b.PartnerB
+		}
+		if a.FirstSeen != b.FirstSeen {
+			return a.FirstSeen < b.FirstSeen
+		}
+		return a.Subject < b.Subject
+	})
+	return tr
+}
+
+// newThreadEntry creates a threadEntry from all mails of a single conversation between the partners of mp.
+func newThreadEntry(mp *sglog.MailPartner, mails []sglog.SingleMail) threadEntry {
+	sortMails(mails)
+	first, last := mails[0], mails[len(mails)-1]
+	entry := threadEntry{
+		Type:      mp.Type,
+		PartnerA:  mp.PartnerA,
+		PartnerB:  mp.PartnerB,
+		Subject:   sglog.NormalizeSubject(first.Subject),
+		FirstSeen: first.Date + " " + first.Time,
+		LastSeen:  last.Date + " " + last.Time,
+	}
+	participants := make(map[string]bool)
+	for _, mail := range mails {
+		entry.Mails++
+		entry.Size = entry.Size + mail.Size
+		participants[mail.From] = true
+		if !entry.Responded && mail.From != first.From {
+			entry.Responded = true
+			start, startErr := time.Parse("2006-01-02 15:04:05", entry.FirstSeen)
+			end, endErr := time.Parse("2006-01-02 15:04:05", mail.Date+" "+mail.Time)
+			if startErr == nil && endErr == nil {
+				entry.ResponseSeconds = int64(end.Sub(start).Seconds())
+			}
+		}
+	}
+	for participant := range participants {
+		entry.Participants = append(entry.Participants, participant)
+	}
+	sort.Strings(entry.Participants)
+	return entry
+}
+
+// ToCSV returns a CSV representation of a threadReport object.
+func (tr *threadReport) ToCSV(withHeader bool) string {
+	var lines []string
+	if withHeader {
+		lines = append(lines, threadReportCSVHeader)
+	}
+	for _, entry := range tr.Threads {
+		lines = append(lines, fmt.Sprintf(threadReportCSVFormat, entry.Type, entry.PartnerA, entry.PartnerB, csvQuote(entry.Subject), entry.Mails, strings.Join(entry.Participants, " "), entry.FirstSeen, entry.LastSeen, entry.Responded, entry.ResponseSeconds))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ToTable returns a human-readable representation of a threadReport object.
+func (tr *threadReport) ToTable() string {
+	var sb strings.Builder
+	sb.WriteString("Conversations by partner\n\n")
+	var tt textTable
+	tt.SetHeader("type", "partner", "subject", "mails", "bytes", "participants", "first seen", "last seen", "response")
+	tt.AlignRight(3, 4, 5, 8)
+	for _, entry := range tr.Threads {
+		response := "-"
+		if entry.Responded {
+			response = formatDuration(entry.ResponseSeconds)
+		}
+		tt.AddRow(entry.Type, entry.PartnerA+" <-> "+entry.PartnerB, entry.Subject, formatCount(entry.Mails), formatBytes(entry.Size), strconv.Itoa(len(entry.Participants)), entry.FirstSeen, entry.LastSeen, response)
+	}
+	sb.WriteString(tt.String())
+	return sb.String()
+}
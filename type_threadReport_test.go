@@ -0,0 +1,43 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"gitlab.com/rbrt-weiler/sophos-sg-smtp-logparser/pkg/sglog"
+)
+
+func TestNewThreadReport(t *testing.T) {
+	question := testMail("a@example.com", "b@else.example.org", "2020-07-18", "10:00:00", "Status", 10)
+	answer := testMail("b@else.example.org", "a@example.com", "2020-07-18", "10:30:00", "Re: STATUS", 20)
+	other := testMail("a@example.com", "b@else.example.org", "2020-07-18", "11:00:00", "[EXTERNAL] Invoice", 40)
+
+	md := testData(question, answer, other)
+	tr := newThreadReport(&md)
+	want := []threadEntry{
+		{Type: "e2i", PartnerA: "b@else.example.org", PartnerB: "a@example.com", Subject: "Status", Mails: 2, Size: 30, Participants: []string{"a@example.com", "b@else.example.org"}, FirstSeen: "2020-07-18 10:00:00", LastSeen: "2020-07-18 10:30:00", Responded: true, ResponseSeconds: 1800},
+		{Type: "e2i", PartnerA: "b@else.example.org", PartnerB: "a@example.com", Subject: "Invoice", Mails: 1, Size: 40, Participants: []string{"a@example.com"}, FirstSeen: "2020-07-18 11:00:00", LastSeen: "2020-07-18 11:00:00"},
+	}
+	if !reflect.DeepEqual(tr.Threads, want) {
+		t.Errorf("newThreadReport() = %+v, want %+v", tr.Threads, want)
+	}
+}
+
+func TestNewThreadReportSameTimestamp(t *testing.T) {
+	question := testMail("a@example.com", "b@else.example.org", "2020-07-18", "10:00:00", "Status", 10)
+	answer := testMail("b@else.example.org", "a@example.com", "2020-07-18", "10:00:00", "Re: STATUS", 20)
+
+	forward := testData(question, answer)
+	backward := testData(answer, question)
+	got, reversed := newThreadReport(&forward), newThreadReport(&backward)
+	if !reflect.DeepEqual(got, reversed) {
+		t.Errorf("newThreadReport() depends on the order of mails: %+v, %+v", got, reversed)
+	}
+	first := question
+	if answer.MailID < question.MailID {
+		first = answer
+	}
+	if len(got.Threads) != 1 || got.Threads[0].Subject != sglog.NormalizeSubject(first.Subject) {
+		t.Errorf("newThreadReport() = %+v, want a single thread started by %q", got.Threads, first.Subject)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gitlab.com/rbrt-weiler/sophos-sg-smtp-logparser/pkg/sglog"
+)
+
+var (
+	// Format strings for CSV output
+	topReportCSVHeader = "category,rank,key,mails,size"
+	topReportCSVFormat = "%s,%d,%s,%d,%d"
+)
+
+// Stores a single ranked entry of a topReport.
+type topEntry struct {
+	Rank  int    `json:"rank"`
+	Key   string `json:"key"`
+	Mails int64  `json:"mails"`
+	Size  int64  `json:"size"`
+}
+
+// Stores rankings of the most active senders, recipients, partners and domains.
+type topReport struct {
+	Limit            int        `json:"limit"`
+	RankBy           string     `json:"rankBy"`
+	Type             string     `json:"type,omitempty"`
+	Senders          []topEntry `json:"senders"`
+	Recipients       []topEntry `json:"recipients"`
+	Partners         []topEntry `json:"partners"`
+	SenderDomains    []topEntry `json:"senderDomains"`
+	RecipientDomains []topEntry `json:"recipientDomains"`
+}
+
+// newTopReport creates a topReport from all mails stored in md.
+// Only mails of type mailType (e.g. "i2e") are considered; an empty mailType matches every mail.
+func newTopReport(md *sglog.MailData, limit int, rankBy string, mailType string) topReport {
+	senders := make(map[string]*topEntry)
+	recipients := make(map[string]*topEntry)
+	partners := make(map[string]*topEntry)
+	senderDomains := make(map[string]*topEntry)
+	recipientDomains := make(map[string]*topEntry)
+
+	count := func(entries map[string]*topEntry, key string, size int64) {
+		entry, ok := entries[key]
+		if !ok {
+			entry = &topEntry{Key: key}
+			entries[key] = entry
+		}
+		entry.Mails++
+		entry.Size = entry.Size + size
+	}
+
+	for partnerKey, partner := range md.Partner {
+		for _, mail := range partner.Mails {
+			if mailType != "" && mail.GetType() != mailType {
+				continue
+			}
+			count(senders, mail.From, mail.Size)
+			count(recipients, mail.To, mail.Size)
+			count(partners, partnerKey, mail.Size)
+			count(senderDomains, displayHost(mail.HostFrom, mail.From), mail.Size)
+			count(recipientDomains, displayHost(mail.HostTo, mail.To), mail.Size)
+		}
+	}
+
+	tr := topReport{Limit: limit, RankBy: rankBy, Type: mailType}
+	tr.Senders = rankTopEntries(senders, limit, rankBy)
+	tr.Recipients = rankTopEntries(recipients, limit, rankBy)
+	tr.Partners = rankTopEntries(partners, limit, rankBy)
+	tr.SenderDomains = rankTopEntries(senderDomains, limit, rankBy)
+	tr.RecipientDomains = rankTopEntries(recipientDomains, limit, rankBy)
+	return tr
+}
+
+// rankTopEntries sorts entries by count or size and returns the first limit elements.
+// Ties are broken by the respective other metric and finally by key.
+func rankTopEntries(entries map[string]*topEntry, limit int, rankBy string) []topEntry {
+	ranked := make([]topEntry, 0, len(entries))
+	for _, entry := range entries {
+		ranked = append(ranked, *entry)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		primaryA, primaryB, secondaryA, secondaryB := a.Mails, b.Mails, a.Size, b.Size
+		if rankBy == "size" {
+			primaryA, primaryB, secondaryA, secondaryB = a.Size, b.Size, a.Mails, b.Mails
+		}
+		if primaryA != primaryB {
+			return primaryA > primaryB
+		}
+		if secondaryA != secondaryB {
+			return secondaryA > secondaryB
+		}
+		return a.Key < b.Key
+	})
+	if limit > 0 && len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	for i := range ranked {
+		ranked[i].Rank = i + 1
+	}
+	return ranked
+}
+
+// categories returns all rankings of a topReport along with their names.
+func (tr *topReport) categories() ([]string, [][]topEntry) {
+	names := []string{"senders", "recipients", "partners", "senderDomains", "recipientDomains"}
+	entries := [][]topEntry{tr.Senders, tr.Recipients, tr.Partners, tr.SenderDomains, tr.RecipientDomains}
+	return names, entries
+}
+
+// ToCSV returns a CSV representation of a topReport object.
+func (tr *topReport) ToCSV(withHeader bool) string {
+	var lines []string
+	if withHeader {
+		lines = append(lines, topReportCSVHeader)
+	}
+	names, rankings := tr.categories()
+	for i, ranking := range rankings {
+		for _, entry := range ranking {
+			lines = append(lines, fmt.Sprintf(topReportCSVFormat, names[i], entry.Rank, entry.Key, entry.Mails, entry.Size))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ToTable returns a human-readable representation of a topReport object.
+func (tr *topReport) ToTable() string {
+	var sb strings.Builder
+	names, rankings := tr.categories()
+	for i, ranking := range rankings {
+		title := fmt.Sprintf("Top %d %s by %s", tr.Limit, names[i], tr.RankBy)
+		if tr.Type != "" {
+			title = fmt.Sprintf("%s (%s only)", title, tr.Type)
+		}
+		sb.WriteString(title)
+		sb.WriteString("\n\n")
+		var tt textTable
+		tt.SetHeader("#", strings.TrimSuffix(names[i], "s"), "mails", "bytes")
+		tt.AlignRight(0, 2, 3)
+		for _, entry := range ranking {
+			tt.AddRow(strconv.Itoa(entry.Rank), entry.Key, formatCount(entry.Mails), formatBytes(entry.Size))
+		}
+		sb.WriteString(tt.String())
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}